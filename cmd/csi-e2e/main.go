@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// csi-e2e runs the CSI E2E tests without a Go toolchain or a checkout
+// of the repository. All manifests are built into the binary.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/onsi/ginkgo"
+
+	"github.com/kubernetes-csi/csi-e2e/test/e2e"
+	"github.com/kubernetes-csi/csi-e2e/test/e2e/storage"
+)
+
+const usage = `Usage: %s <command> [flags] [arguments]
+
+Commands:
+  run     run the tests, supports the same flags as "go test ./test/e2e"
+  list    print all tests and whether they would get skipped
+  render  print the manifests of the given drivers (default: all)
+          as they would get deployed for a test
+
+Run "%s <command> -h" for the flags of a command.
+`
+
+// testingT records whether the tests failed.
+type testingT struct {
+	failed bool
+}
+
+func (t *testingT) Fail() {
+	t.failed = true
+}
+
+func main() {
+	program := os.Args[0]
+	if len(os.Args) < 2 {
+		fmt.Fprintf(os.Stderr, usage, program, program)
+		os.Exit(2)
+	}
+	command := os.Args[1]
+	// Flags are parsed by e2e.Setup, which only looks at os.Args.
+	os.Args = append([]string{program + " " + command}, os.Args[2:]...)
+
+	switch command {
+	case "run":
+		e2e.Setup()
+		t := &testingT{}
+		e2e.RunE2ETests(t)
+		if t.failed {
+			os.Exit(1)
+		}
+	case "list":
+		e2e.Setup()
+		t := &testingT{}
+		e2e.ListE2ETests(t, os.Stdout)
+		if t.failed {
+			os.Exit(1)
+		}
+	case "render":
+		namespace := flag.String("render.namespace", "csi-e2e", "namespace and unique name used for patching the manifests")
+		// Keep log messages out of the YAML output. No tests run,
+		// so nothing else depends on the GinkgoWriter.
+		ginkgo.GinkgoWriter = os.Stderr
+		e2e.Setup()
+		if err := storage.RenderManifests(os.Stdout, *namespace, flag.Args()...); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	case "help", "-h", "-help", "--help":
+		fmt.Fprintf(os.Stdout, usage, program, program)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", command)
+		fmt.Fprintf(os.Stderr, usage, program, program)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// csi-proxy sits between the clients of a CSI driver and the driver
+// itself and logs all gRPC calls as JSON on stdout.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/kubernetes-csi/csi-e2e/pkg/csiproxy"
+)
+
+var (
+	listen = flag.String("listen", "/csi/csi.sock", "unix domain socket for incoming connections")
+	target = flag.String("target", "/csi/csi-driver.sock", "unix domain socket of the CSI driver")
+)
+
+func main() {
+	flag.Parse()
+
+	proxy, err := csiproxy.New(*target, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		proxy.Stop()
+	}()
+
+	fmt.Fprintf(os.Stderr, "proxying %s -> %s\n", *listen, *target)
+	if err := proxy.Run(*listen); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csiproxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Call is one recorded gRPC call. The proxy writes each call as one
+// line of JSON.
+type Call struct {
+	// Time when the call was received.
+	Time time.Time `json:"time"`
+	// Full gRPC method name, for example /csi.v1.Node/NodePublishVolume.
+	FullMethod string `json:"method"`
+	// Duration of the call in the driver.
+	Duration time.Duration `json:"duration"`
+	// The gRPC status code as string, "OK" for success.
+	Code string `json:"code"`
+	// The error message, empty for success.
+	Error string `json:"error,omitempty"`
+	// Selected fields from the request and response.
+	Request  map[string]interface{} `json:"request,omitempty"`
+	Response map[string]interface{} `json:"response,omitempty"`
+}
+
+// Method returns the method name without the service, for
+// example NodePublishVolume.
+func (c Call) Method() string {
+	return methodName(c.FullMethod)
+}
+
+// VolumeID returns the ID of the volume that the call was about or
+// an empty string if not known. For CreateVolume, the ID is only
+// known when the call succeeded.
+func (c Call) VolumeID() string {
+	if id, ok := c.Request[volumeID.name].(string); ok {
+		return id
+	}
+	if volume, ok := c.Response["volume"].(map[string]interface{}); ok {
+		if id, ok := volume[volumeID.name].(string); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// String returns the JSON representation of the call.
+func (c Call) String() string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+// Prefix is the beginning of each log line written for a call. It is
+// used to find calls in the output of the proxy, which might contain
+// other messages.
+const Prefix = "CSI call: "
+
+// ParseCalls finds all calls in the proxy output.
+func ParseCalls(output string) ([]Call, error) {
+	var calls []Call
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	// Lines can get long.
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		index := strings.Index(line, Prefix)
+		if index < 0 {
+			continue
+		}
+		var call Call
+		if err := json.Unmarshal([]byte(line[index+len(Prefix):]), &call); err != nil {
+			return nil, err
+		}
+		calls = append(calls, call)
+	}
+	return calls, scanner.Err()
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csiproxy
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// The proxy does not depend on the CSI spec. Instead it decodes
+// the protobuf wire format itself and only extracts those fields
+// that are needed for checking the call sequence. That has the
+// advantage that secrets are never logged and that it works for
+// CSI 0.3 and 1.0, which use the same field numbers for these
+// fields.
+
+type fieldKind int
+
+const (
+	stringField fieldKind = iota
+	boolField
+	intField
+	messageField
+)
+
+type field struct {
+	name    string
+	kind    fieldKind
+	message fields
+}
+
+// fields maps protobuf field numbers to field descriptions.
+type fields map[uint64]field
+
+var volumeID = field{name: "volume_id", kind: stringField}
+
+// requestFields defines what is extracted from the request of
+// certain methods. The key is the method name without the service.
+var requestFields = map[string]fields{
+	"CreateVolume": {
+		1: {name: "name", kind: stringField},
+	},
+	"DeleteVolume": {
+		1: volumeID,
+	},
+	"ControllerPublishVolume": {
+		1: volumeID,
+		2: {name: "node_id", kind: stringField},
+		4: {name: "readonly", kind: boolField},
+	},
+	"ControllerUnpublishVolume": {
+		1: volumeID,
+		2: {name: "node_id", kind: stringField},
+	},
+	"NodeStageVolume": {
+		1: volumeID,
+		3: {name: "staging_target_path", kind: stringField},
+	},
+	"NodeUnstageVolume": {
+		1: volumeID,
+		2: {name: "staging_target_path", kind: stringField},
+	},
+	"NodePublishVolume": {
+		1: volumeID,
+		3: {name: "staging_target_path", kind: stringField},
+		4: {name: "target_path", kind: stringField},
+		6: {name: "readonly", kind: boolField},
+	},
+	"NodeUnpublishVolume": {
+		1: volumeID,
+		2: {name: "target_path", kind: stringField},
+	},
+}
+
+// responseFields is the counterpart of requestFields for responses.
+var responseFields = map[string]fields{
+	"CreateVolume": {
+		1: {name: "volume", kind: messageField, message: fields{
+			1: {name: "capacity_bytes", kind: intField},
+			2: volumeID,
+		}},
+	},
+}
+
+// methodName strips the service from a full gRPC method name like
+// /csi.v1.Node/NodePublishVolume.
+func methodName(fullMethod string) string {
+	return path.Base(fullMethod)
+}
+
+var errTruncated = errors.New("truncated message")
+
+// decode extracts the known fields from a message in protobuf wire
+// format. Unknown fields are skipped.
+func decode(data []byte, known fields) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for len(data) > 0 {
+		key, n := proto.DecodeVarint(data)
+		if n == 0 {
+			return nil, errTruncated
+		}
+		data = data[n:]
+		number, wireType := key>>3, key&7
+
+		var value uint64
+		var bytes []byte
+		switch wireType {
+		case proto.WireVarint:
+			value, n = proto.DecodeVarint(data)
+			if n == 0 {
+				return nil, errTruncated
+			}
+			data = data[n:]
+		case proto.WireFixed64:
+			if len(data) < 8 {
+				return nil, errTruncated
+			}
+			data = data[8:]
+		case proto.WireFixed32:
+			if len(data) < 4 {
+				return nil, errTruncated
+			}
+			data = data[4:]
+		case proto.WireBytes:
+			length, n := proto.DecodeVarint(data)
+			if n == 0 || uint64(len(data)-n) < length {
+				return nil, errTruncated
+			}
+			bytes = data[n : n+int(length)]
+			data = data[n+int(length):]
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d", wireType)
+		}
+
+		f, ok := known[number]
+		if !ok {
+			continue
+		}
+		switch f.kind {
+		case stringField:
+			result[f.name] = string(bytes)
+		case boolField:
+			result[f.name] = value != 0
+		case intField:
+			result[f.name] = int64(value)
+		case messageField:
+			message, err := decode(bytes, f.message)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", f.name, err)
+			}
+			result[f.name] = message
+		}
+	}
+	return result, nil
+}
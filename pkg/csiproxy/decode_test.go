@@ -0,0 +1,367 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csiproxy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// The CSI spec is not a dependency of this repository. The following
+// messages use the same field numbers and types as the CSI 1.0 spec
+// (csi.proto), so they have the same wire format. The vendored
+// protobuf library encodes them based on the struct tags.
+
+type capacityRange struct {
+	RequiredBytes int64 `protobuf:"varint,1,opt,name=required_bytes,json=requiredBytes,proto3"`
+	LimitBytes    int64 `protobuf:"varint,2,opt,name=limit_bytes,json=limitBytes,proto3"`
+}
+
+func (m *capacityRange) Reset()         { *m = capacityRange{} }
+func (m *capacityRange) String() string { return proto.CompactTextString(m) }
+func (*capacityRange) ProtoMessage()    {}
+
+type mountVolume struct {
+	FsType     string   `protobuf:"bytes,1,opt,name=fs_type,json=fsType,proto3"`
+	MountFlags []string `protobuf:"bytes,2,rep,name=mount_flags,json=mountFlags,proto3"`
+}
+
+func (m *mountVolume) Reset()         { *m = mountVolume{} }
+func (m *mountVolume) String() string { return proto.CompactTextString(m) }
+func (*mountVolume) ProtoMessage()    {}
+
+type accessMode struct {
+	Mode int32 `protobuf:"varint,1,opt,name=mode,proto3"`
+}
+
+func (m *accessMode) Reset()         { *m = accessMode{} }
+func (m *accessMode) String() string { return proto.CompactTextString(m) }
+func (*accessMode) ProtoMessage()    {}
+
+// volumeCapability has the mount variant of the access_type oneof
+// as a plain field, which is encoded the same way.
+type volumeCapability struct {
+	Mount      *mountVolume `protobuf:"bytes,2,opt,name=mount,proto3"`
+	AccessMode *accessMode  `protobuf:"bytes,3,opt,name=access_mode,json=accessMode,proto3"`
+}
+
+func (m *volumeCapability) Reset()         { *m = volumeCapability{} }
+func (m *volumeCapability) String() string { return proto.CompactTextString(m) }
+func (*volumeCapability) ProtoMessage()    {}
+
+type createVolumeRequest struct {
+	Name               string              `protobuf:"bytes,1,opt,name=name,proto3"`
+	CapacityRange      *capacityRange      `protobuf:"bytes,2,opt,name=capacity_range,json=capacityRange,proto3"`
+	VolumeCapabilities []*volumeCapability `protobuf:"bytes,3,rep,name=volume_capabilities,json=volumeCapabilities,proto3"`
+	Parameters         map[string]string   `protobuf:"bytes,4,rep,name=parameters,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Secrets            map[string]string   `protobuf:"bytes,5,rep,name=secrets,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *createVolumeRequest) Reset()         { *m = createVolumeRequest{} }
+func (m *createVolumeRequest) String() string { return proto.CompactTextString(m) }
+func (*createVolumeRequest) ProtoMessage()    {}
+
+type volume struct {
+	CapacityBytes int64             `protobuf:"varint,1,opt,name=capacity_bytes,json=capacityBytes,proto3"`
+	VolumeId      string            `protobuf:"bytes,2,opt,name=volume_id,json=volumeId,proto3"`
+	VolumeContext map[string]string `protobuf:"bytes,3,rep,name=volume_context,json=volumeContext,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *volume) Reset()         { *m = volume{} }
+func (m *volume) String() string { return proto.CompactTextString(m) }
+func (*volume) ProtoMessage()    {}
+
+type createVolumeResponse struct {
+	Volume *volume `protobuf:"bytes,1,opt,name=volume,proto3"`
+}
+
+func (m *createVolumeResponse) Reset()         { *m = createVolumeResponse{} }
+func (m *createVolumeResponse) String() string { return proto.CompactTextString(m) }
+func (*createVolumeResponse) ProtoMessage()    {}
+
+type controllerPublishVolumeRequest struct {
+	VolumeId         string            `protobuf:"bytes,1,opt,name=volume_id,json=volumeId,proto3"`
+	NodeId           string            `protobuf:"bytes,2,opt,name=node_id,json=nodeId,proto3"`
+	VolumeCapability *volumeCapability `protobuf:"bytes,3,opt,name=volume_capability,json=volumeCapability,proto3"`
+	Readonly         bool              `protobuf:"varint,4,opt,name=readonly,proto3"`
+	Secrets          map[string]string `protobuf:"bytes,5,rep,name=secrets,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *controllerPublishVolumeRequest) Reset()         { *m = controllerPublishVolumeRequest{} }
+func (m *controllerPublishVolumeRequest) String() string { return proto.CompactTextString(m) }
+func (*controllerPublishVolumeRequest) ProtoMessage()    {}
+
+type nodePublishVolumeRequest struct {
+	VolumeId          string            `protobuf:"bytes,1,opt,name=volume_id,json=volumeId,proto3"`
+	PublishContext    map[string]string `protobuf:"bytes,2,rep,name=publish_context,json=publishContext,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	StagingTargetPath string            `protobuf:"bytes,3,opt,name=staging_target_path,json=stagingTargetPath,proto3"`
+	TargetPath        string            `protobuf:"bytes,4,opt,name=target_path,json=targetPath,proto3"`
+	VolumeCapability  *volumeCapability `protobuf:"bytes,5,opt,name=volume_capability,json=volumeCapability,proto3"`
+	Readonly          bool              `protobuf:"varint,6,opt,name=readonly,proto3"`
+	Secrets           map[string]string `protobuf:"bytes,7,rep,name=secrets,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	VolumeContext     map[string]string `protobuf:"bytes,8,rep,name=volume_context,json=volumeContext,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *nodePublishVolumeRequest) Reset()         { *m = nodePublishVolumeRequest{} }
+func (m *nodePublishVolumeRequest) String() string { return proto.CompactTextString(m) }
+func (*nodePublishVolumeRequest) ProtoMessage()    {}
+
+var capability = &volumeCapability{
+	Mount:      &mountVolume{FsType: "ext4", MountFlags: []string{"noatime"}},
+	AccessMode: &accessMode{Mode: 1},
+}
+
+func TestDecode(t *testing.T) {
+	testcases := []struct {
+		name     string
+		message  proto.Message
+		known    fields
+		expected map[string]interface{}
+	}{
+		{
+			name: "CreateVolume request",
+			message: &createVolumeRequest{
+				Name:               "pvc-1",
+				CapacityRange:      &capacityRange{RequiredBytes: 1024, LimitBytes: 2048},
+				VolumeCapabilities: []*volumeCapability{capability},
+				Parameters:         map[string]string{"foo": "bar"},
+				Secrets:            map[string]string{"password": "secret"},
+			},
+			known: requestFields["CreateVolume"],
+			expected: map[string]interface{}{
+				"name": "pvc-1",
+			},
+		},
+		{
+			name: "CreateVolume response",
+			message: &createVolumeResponse{
+				Volume: &volume{
+					CapacityBytes: 1 << 40,
+					VolumeId:      "vol-1",
+					VolumeContext: map[string]string{"foo": "bar"},
+				},
+			},
+			known: responseFields["CreateVolume"],
+			expected: map[string]interface{}{
+				"volume": map[string]interface{}{
+					"capacity_bytes": int64(1 << 40),
+					"volume_id":      "vol-1",
+				},
+			},
+		},
+		{
+			name: "ControllerPublishVolume request",
+			message: &controllerPublishVolumeRequest{
+				VolumeId:         "vol-1",
+				NodeId:           "node-1",
+				VolumeCapability: capability,
+				Readonly:         true,
+				Secrets:          map[string]string{"password": "secret"},
+			},
+			known: requestFields["ControllerPublishVolume"],
+			expected: map[string]interface{}{
+				"volume_id": "vol-1",
+				"node_id":   "node-1",
+				"readonly":  true,
+			},
+		},
+		{
+			name: "NodePublishVolume request",
+			message: &nodePublishVolumeRequest{
+				VolumeId:          "vol-1",
+				PublishContext:    map[string]string{"foo": "bar"},
+				StagingTargetPath: "/staging",
+				TargetPath:        "/target",
+				VolumeCapability:  capability,
+				Readonly:          true,
+				Secrets:           map[string]string{"password": "secret"},
+				VolumeContext:     map[string]string{"foo": "bar"},
+			},
+			known: requestFields["NodePublishVolume"],
+			expected: map[string]interface{}{
+				"volume_id":           "vol-1",
+				"staging_target_path": "/staging",
+				"target_path":         "/target",
+				"readonly":            true,
+			},
+		},
+		{
+			name: "NodePublishVolume request, not read-only",
+			message: &nodePublishVolumeRequest{
+				VolumeId:   "vol-1",
+				TargetPath: "/target",
+			},
+			known: requestFields["NodePublishVolume"],
+			// Fields with default values are not
+			// encoded and thus cannot be decoded.
+			expected: map[string]interface{}{
+				"volume_id":   "vol-1",
+				"target_path": "/target",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		data, err := proto.Marshal(tc.message)
+		if err != nil {
+			t.Fatalf("%s: encoding failed: %v", tc.name, err)
+		}
+		result, err := decode(data, tc.known)
+		if err != nil {
+			t.Errorf("%s: decoding failed: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, result)
+		}
+		if id := (Call{Request: result, Response: result}).VolumeID(); id != "" && id != "vol-1" {
+			t.Errorf("%s: unexpected volume ID %q", tc.name, id)
+		}
+
+		// Any truncation must be detected.
+		for i := 1; i < len(data); i++ {
+			if _, err := decode(data[:i], tc.known); err == nil && truncatedField(data, i) {
+				t.Errorf("%s: truncation at %d of %d bytes not detected", tc.name, i, len(data))
+			}
+		}
+	}
+}
+
+// truncatedField returns true if cutting the message at the given
+// offset splits a top-level field. Cutting between fields results in
+// a valid message.
+func truncatedField(data []byte, offset int) bool {
+	for pos := 0; pos < len(data); {
+		if pos == offset {
+			return false
+		}
+		key, n := proto.DecodeVarint(data[pos:])
+		pos += n
+		switch key & 7 {
+		case proto.WireVarint:
+			_, n = proto.DecodeVarint(data[pos:])
+			pos += n
+		case proto.WireBytes:
+			length, n := proto.DecodeVarint(data[pos:])
+			pos += n + int(length)
+		}
+		if pos > offset {
+			return true
+		}
+	}
+	return false
+}
+
+func TestVolumeID(t *testing.T) {
+	request, err := proto.Marshal(&nodePublishVolumeRequest{VolumeId: "vol-1", TargetPath: "/target"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := proto.Marshal(&createVolumeResponse{Volume: &volume{VolumeId: "vol-2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publish := Call{Request: decodeFields(request, requestFields["NodePublishVolume"])}
+	if id := publish.VolumeID(); id != "vol-1" {
+		t.Errorf("NodePublishVolume: expected volume ID vol-1, got %q", id)
+	}
+	create := Call{Response: decodeFields(response, responseFields["CreateVolume"])}
+	if id := create.VolumeID(); id != "vol-2" {
+		t.Errorf("CreateVolume: expected volume ID vol-2, got %q", id)
+	}
+}
+
+func TestParseCalls(t *testing.T) {
+	request, err := proto.Marshal(&nodePublishVolumeRequest{VolumeId: "vol-1", TargetPath: "/target", Readonly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := proto.Marshal(&createVolumeResponse{Volume: &volume{CapacityBytes: 1024, VolumeId: "vol-2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2018, 11, 1, 10, 0, 0, 0, time.UTC)
+	publish := Call{
+		Time:       start,
+		FullMethod: "/csi.v1.Node/NodePublishVolume",
+		Duration:   time.Millisecond,
+		Code:       "OK",
+		Request:    decodeFields(request, requestFields["NodePublishVolume"]),
+	}
+	create := Call{
+		Time:       start.Add(time.Second),
+		FullMethod: "/csi.v1.Controller/CreateVolume",
+		Duration:   time.Second,
+		Code:       "OK",
+		Response:   decodeFields(response, responseFields["CreateVolume"]),
+	}
+	failed := Call{
+		Time:       start.Add(2 * time.Second),
+		FullMethod: "/csi.v1.Controller/DeleteVolume",
+		Code:       "NotFound",
+		Error:      "no such volume",
+	}
+
+	output := strings.Join([]string{
+		"I1101 10:00:00.000000       1 main.go:42] proxy listening on /csi/csi.sock",
+		Prefix + publish.String(),
+		"not JSON: {",
+		"",
+		"I1101 10:00:01.000000       1 proxy.go:127] " + Prefix + create.String(),
+		`{"time": "2018-11-01T10:00:00Z", "method": "/csi.v1.Node/NodeGetInfo"}`,
+		Prefix + failed.String(),
+		"W1101 10:00:03.000000       1 main.go:50] shutting down",
+	}, "\n")
+	calls, err := ParseCalls(output)
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+	if len(calls) != 3 {
+		t.Fatalf("expected 3 calls, got %d: %v", len(calls), calls)
+	}
+
+	for i, expected := range []struct {
+		method, code, volumeID string
+	}{
+		{"NodePublishVolume", "OK", "vol-1"},
+		{"CreateVolume", "OK", "vol-2"},
+		{"DeleteVolume", "NotFound", ""},
+	} {
+		call := calls[i]
+		if call.Method() != expected.method || call.Code != expected.code || call.VolumeID() != expected.volumeID {
+			t.Errorf("call #%d: expected %s/%s/%q, got %s/%s/%q", i, expected.method, expected.code, expected.volumeID,
+				call.Method(), call.Code, call.VolumeID())
+		}
+	}
+	if !calls[0].Time.Equal(start) || calls[0].Duration != time.Millisecond {
+		t.Errorf("call #0: wrong time or duration: %v", calls[0])
+	}
+	if readonly, _ := calls[0].Request["readonly"].(bool); !readonly {
+		t.Errorf("call #0: readonly not set: %v", calls[0])
+	}
+	if calls[2].Error != "no such volume" {
+		t.Errorf("call #2: wrong error: %v", calls[2])
+	}
+
+	if _, err := ParseCalls(Prefix + "{"); err == nil {
+		t.Error("invalid JSON after prefix not detected")
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package csiproxy implements a gRPC proxy which sits between the
+// clients of a CSI driver (sidecars, kubelet) and the driver itself
+// and records all calls. The calls are written as lines of JSON and
+// can be parsed again with ParseCalls.
+package csiproxy
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Proxy forwards all gRPC calls to the target.
+type Proxy struct {
+	conn   *grpc.ClientConn
+	server *grpc.Server
+	out    io.Writer
+	mutex  sync.Mutex
+}
+
+// New connects to the driver at the unix domain socket and returns a
+// proxy which writes calls to the given writer.
+func New(target string, out io.Writer) (*Proxy, error) {
+	conn, err := grpc.Dial(target,
+		grpc.WithInsecure(),
+		grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout("unix", addr, timeout)
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %v", target, err)
+	}
+	p := &Proxy{
+		conn: conn,
+		out:  out,
+	}
+	p.server = grpc.NewServer(
+		grpc.CustomCodec(rawCodec{}),
+		grpc.UnknownServiceHandler(p.handle),
+	)
+	return p, nil
+}
+
+// Run listens on the unix domain socket and serves requests until
+// Stop is called or an error occurs. A stale socket gets removed
+// first.
+func (p *Proxy) Run(endpoint string) error {
+	if err := os.Remove(endpoint); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove %s: %v", endpoint, err)
+	}
+	listener, err := net.Listen("unix", endpoint)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %v", endpoint, err)
+	}
+	return p.server.Serve(listener)
+}
+
+// Stop closes the listener and the connection to the driver.
+func (p *Proxy) Stop() {
+	p.server.Stop()
+	p.conn.Close()
+}
+
+// handle forwards one call. All CSI calls are unary, so there is
+// exactly one request and one response.
+func (p *Proxy) handle(srv interface{}, stream grpc.ServerStream) error {
+	fullMethod, ok := grpc.MethodFromServerStream(stream)
+	if !ok {
+		return fmt.Errorf("method name not found")
+	}
+	var request, response []byte
+	if err := stream.RecvMsg(&request); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+	call := Call{
+		Time:       time.Now(),
+		FullMethod: fullMethod,
+	}
+	err := p.conn.Invoke(ctx, fullMethod, &request, &response, grpc.CallCustomCodec(rawCodec{}))
+	call.Duration = time.Since(call.Time)
+	s, _ := status.FromError(err)
+	call.Code = s.Code().String()
+	call.Error = s.Message()
+	call.Request = decodeFields(request, requestFields[methodName(fullMethod)])
+	if err == nil {
+		call.Response = decodeFields(response, responseFields[methodName(fullMethod)])
+	}
+	p.record(call)
+
+	if err != nil {
+		return err
+	}
+	return stream.SendMsg(&response)
+}
+
+func (p *Proxy) record(call Call) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	fmt.Fprintf(p.out, "%s%s\n", Prefix, call)
+}
+
+// decodeFields never fails, decoding errors get recorded instead.
+func decodeFields(data []byte, known fields) map[string]interface{} {
+	if known == nil {
+		return nil
+	}
+	result, err := decode(data, known)
+	if err != nil {
+		return map[string]interface{}{"decoding error": err.Error()}
+	}
+	return result
+}
+
+// rawCodec passes messages through without modifying them.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	data, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type %T", v)
+	}
+	return *data, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	buffer, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected type %T", v)
+	}
+	*buffer = append((*buffer)[:0], data...)
+	return nil
+}
+
+func (rawCodec) String() string {
+	return "raw"
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Registered during variable initialization because flags get parsed
+// in the init function of e2e_test.go.
+var configFile = flag.String("config", "",
+	"YAML file with a map from flag names (without leading hyphen) to values. Lists are used for flags which may be given more than once. Flags on the command line take precedence.")
+
+// applyConfigFile sets all flags listed in the config file which were
+// not set on the command line. Because all framework.TestContext
+// fields and csi-e2e settings are configured through flags, this
+// covers all of them and uses the same validation and defaults.
+func applyConfigFile(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	data, err = yaml.YAMLToJSON(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	// Numbers are kept as they are written instead of converting
+	// them to float64, which would turn large integers into
+	// exponential notation.
+	var settings map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&settings); err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+
+	setOnCommandLine := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		setOnCommandLine[f.Name] = true
+	})
+
+	// Sorted for deterministic error messages.
+	var names []string
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "config" {
+			return fmt.Errorf("%s: config files cannot be nested", filename)
+		}
+		if flag.Lookup(name) == nil {
+			return fmt.Errorf("%s: unknown flag %q", filename, name)
+		}
+		if setOnCommandLine[name] {
+			continue
+		}
+		values, ok := settings[name].([]interface{})
+		if !ok {
+			values = []interface{}{settings[name]}
+		}
+		for _, value := range values {
+			switch value.(type) {
+			case map[string]interface{}, []interface{}, nil:
+				return fmt.Errorf("%s: flag %q: value must be a string, number or boolean", filename, name)
+			}
+			if err := flag.Set(name, fmt.Sprint(value)); err != nil {
+				return fmt.Errorf("%s: flag %q: %v", filename, name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/api/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/test/e2e/framework"
+)
+
+const connectivityPodManifest = "test/e2e/manifests/connectivity/pod.yaml"
+
+// The checks done by the connectivity pod in the pod network.
+var podNetworkChecks = []string{"dns", "apiserver"}
+
+// The checks done by the connectivity pod in the host network.
+var hostNetworkChecks = []string{"apiserver"}
+
+// hostNetworkSuffix is added to the name of checks in the host
+// network.
+const hostNetworkSuffix = " (host network)"
+
+// The checks in the order in which they are reported.
+var connectivityChecks = []string{"dns", "apiserver", "apiserver" + hostNetworkSuffix}
+
+// checkConnectivity runs a pod on each node that test drivers may
+// get pinned to and checks that DNS and the API server are reachable
+// from there, because the CSI sidecars depend on both. Driver pods
+// may also use the host network, like the hostpath driver, therefore
+// the API server is also checked from there. The result is logged as
+// one table row per node. The returned error lists all nodes where
+// some check failed.
+func checkConnectivity(c clientset.Interface) error {
+	nodes, results, err := runProbePods(c, connectivityPodManifest, podNetworkChecks, nil)
+	if err != nil {
+		return err
+	}
+	// Pods in the host network use the DNS configuration of the
+	// node, which cannot resolve cluster names, and neither do
+	// the drivers need that.
+	_, hostResults, err := runProbePods(c, connectivityPodManifest, hostNetworkChecks, func(pod *v1.Pod) {
+		pod.Spec.HostNetwork = true
+		var containers []v1.Container
+		for _, container := range pod.Spec.Containers {
+			if container.Name != "dns" {
+				containers = append(containers, container)
+			}
+		}
+		pod.Spec.Containers = containers
+	})
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		for _, check := range hostNetworkChecks {
+			result, ok := hostResults[node][check]
+			if !ok {
+				result = "unknown: not checked"
+			}
+			results[node][check+hostNetworkSuffix] = result
+		}
+	}
+
+	var buffer bytes.Buffer
+	w := tabwriter.NewWriter(&buffer, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "NODE\t%s\n", strings.ToUpper(strings.Join(connectivityChecks, "\t")))
+	var failed []string
+	for _, node := range nodes {
+		result := results[node]
+		fmt.Fprintf(w, "%s", node)
+		ok := true
+		for _, check := range connectivityChecks {
+			fmt.Fprintf(w, "\t%s", result[check])
+			ok = ok && result[check] == "ok"
+		}
+		fmt.Fprintln(w)
+		if !ok {
+			failed = append(failed, node)
+		}
+	}
+	w.Flush()
+	framework.Logf("Connectivity of nodes:\n%s", buffer.String())
+
+	if len(failed) > 0 {
+		return fmt.Errorf("DNS or API server not reachable from nodes %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
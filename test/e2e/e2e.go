@@ -17,21 +17,33 @@ limitations under the License.
 package e2e
 
 import (
-	"testing"
-	"time"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
 
 	"github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/config"
 	"github.com/onsi/gomega"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/kubernetes/pkg/version"
 	"k8s.io/kubernetes/test/e2e/framework"
 	"k8s.io/kubernetes/test/e2e/framework/ginkgowrapper"
-	"k8s.io/kubernetes/test/e2e/manifest"
-	testutils "k8s.io/kubernetes/test/utils"
+	"k8s.io/kubernetes/test/e2e/framework/testfiles"
+
+	"github.com/kubernetes-csi/csi-e2e/test/e2e/generated"
+	"github.com/kubernetes-csi/csi-e2e/test/e2e/storage"
 )
 
+// Registered during variable initialization because flags get parsed
+// in the init function of e2e_test.go.
+var cleanStart = flag.Bool("csi.clean-start", false,
+	"Before running tests, delete namespaces and cluster-scoped objects created by earlier csi-e2e runs, including stuck PVs of drivers which no longer exist. Unlike -clean-start, other namespaces are not touched. Must not be used while other csi-e2e runs are active in the cluster.")
+
 // There are certain operations we only want to run once per overall test invocation
 // (such as deleting old namespaces, or verifying that all system pods are running.
 // Because of the way Ginkgo runs tests in parallel, we must use SynchronizedBeforeSuite
@@ -69,6 +81,13 @@ var _ = ginkgo.SynchronizedBeforeSuite(func() []byte {
 		}
 	}
 
+	// Delete only what earlier runs of csi-e2e left behind.
+	if *cleanStart {
+		if err := storage.CleanStart(); err != nil {
+			framework.Failf("Error deleting objects of earlier runs: %v", err)
+		}
+	}
+
 	// In large clusters we may get to this point but still have a bunch
 	// of nodes without Routes created. Since this would make a node
 	// unschedulable, we need to wait until all of them are schedulable.
@@ -86,7 +105,9 @@ var _ = ginkgo.SynchronizedBeforeSuite(func() []byte {
 	if err := framework.WaitForPodsRunningReady(c, metav1.NamespaceSystem, int32(framework.TestContext.MinStartupPods), int32(framework.TestContext.AllowedNotReadyNodes), podStartupTimeout, map[string]string{}); err != nil {
 		framework.DumpAllNamespaceInfo(c, metav1.NamespaceSystem)
 		framework.LogFailedContainers(c, metav1.NamespaceSystem, framework.Logf)
-		runKubernetesServiceTestContainer(c, metav1.NamespaceDefault)
+		if err := checkConnectivity(c); err != nil {
+			framework.Logf("%v", err)
+		}
 		framework.Failf("Error waiting for all pods to be running and ready: %v", err)
 	}
 
@@ -94,6 +115,15 @@ var _ = ginkgo.SynchronizedBeforeSuite(func() []byte {
 		framework.Logf("WARNING: Waiting for all daemonsets to be ready failed: %v", err)
 	}
 
+	if *preflight {
+		// The CSI sidecars need DNS and the API server on all nodes
+		// where the driver might run.
+		if err := checkConnectivity(c); err != nil {
+			framework.Failf("Connectivity check failed: %v", err)
+		}
+		checkCSIPrerequisites(c)
+	}
+
 	// Log the version of the server and this client.
 	framework.Logf("e2e test version: %s", version.Get().GitVersion)
 
@@ -110,61 +140,112 @@ var _ = ginkgo.SynchronizedBeforeSuite(func() []byte {
 	// Reference common test to make the import valid.
 	// commontest.CurrentSuite = commontest.E2E
 
+	// All Ginkgo nodes run on this machine and share this
+	// directory.
+	dir, err := ioutil.TempDir("", "csi-e2e-")
+	framework.ExpectNoError(err, "create temporary directory")
+	data = []byte(dir)
+
 	return data
 
 }, func(data []byte) {
 	// Run on all Ginkgo nodes
+	runDir = string(data)
+	storage.SetUniqueNamesDir(runDir)
+
+	// Drivers and test suites get skipped when the cluster does
+	// not support them.
+	c, err := framework.LoadClientset()
+	if err != nil {
+		framework.Failf("Error loading client: %s", err)
+	}
+	if err := storage.DetectCluster(c); err != nil {
+		framework.Failf("Error inspecting cluster: %v", err)
+	}
 })
 
+// runDir is a temporary directory for the current test run.
+var runDir string
+
 // Similar to SynchornizedBeforeSuite, we want to run some operations only once (such as collecting cluster logs).
 // Here, the order of functions is reversed; first, the function which runs everywhere,
 // and then the function that only runs on the first Ginkgo node.
 var _ = ginkgo.SynchronizedAfterSuite(func() {
 	// Run on all Ginkgo nodes
 	framework.Logf("Running AfterSuite actions on all node")
+	storage.CleanupSharedDrivers()
 	framework.RunCleanupActions()
 }, func() {
 	// Run only Ginkgo on node 1
 	framework.Logf("Running AfterSuite actions on node 1")
+	storage.LogSlowestPhases(framework.TestContext.ReportDir)
+	storage.CheckLeakedObjects()
+	if runDir != "" {
+		os.RemoveAll(runDir)
+	}
 })
 
+// Setup parses the command line and defines all tests. It must be
+// called once before RunE2ETests or ListE2ETests.
+func Setup() {
+	log.SetOutput(ginkgo.GinkgoWriter)
+
+	// Register framework flags, then handle flags.
+	framework.HandleFlags()
+	if *configFile != "" {
+		if err := applyConfigFile(*configFile); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	framework.AfterReadingAllFlags(&framework.TestContext)
+	// Makes test namespaces recognizable for -csi.clean-start.
+	framework.TestContext.CreateTestingNS = storage.CreateTestingNS
+
+	// Files are read from the repository only when asked for,
+	// which allows testing local modifications without
+	// regenerating the embedded files.
+	if framework.TestContext.RepoRoot != "" {
+		testfiles.AddFileSource(testfiles.RootFileSource{Root: framework.TestContext.RepoRoot})
+	}
+	testfiles.AddFileSource(generated.FileSource())
+
+	// The set of tests depends on flags, therefore tests
+	// can only be defined now.
+	if err := storage.DefineTests(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(2)
+	}
+}
+
 // RunE2ETests checks configuration parameters (specified through flags) and then runs
 // E2E tests using the Ginkgo runner.
 // This function is called on each Ginkgo node in parallel mode.
-func RunE2ETests(t *testing.T) {
-	gomega.RegisterFailHandler(ginkgowrapper.Fail)
-	ginkgo.RunSpecs(t, "Kubernetes CSI E2E suite")
+func RunE2ETests(t ginkgo.GinkgoTestingT) {
+	// The storage fail handler dumps the state of a failed test
+	// before its objects get deleted.
+	gomega.RegisterFailHandler(storage.FailHandler)
+	// Run tests through the Ginkgo runner with output to console + JUnit for Jenkins
+	// and a summary of the results per driver, test suite and test pattern.
+	// Tests retried with -ginkgo.flakeAttempts are reported once, as
+	// passed, flaky or failed.
+	var r []ginkgo.Reporter
+	if framework.TestContext.ReportDir != "" {
+		r = append(r, storage.NewFlakeReporter(framework.TestContext.ReportDir,
+			storage.NewJUnitReporter(path.Join(framework.TestContext.ReportDir, fmt.Sprintf("junit_%v%02d.xml", framework.TestContext.ReportPrefix, config.GinkgoConfig.ParallelNode))),
+			storage.NewMatrixReporter(framework.TestContext.ReportDir),
+		))
+	}
+	ginkgo.RunSpecsWithDefaultAndCustomReporters(t, "Kubernetes CSI E2E suite", r)
 }
 
-// Run a test container to try and contact the Kubernetes api-server from a pod, wait for it
-// to flip to Ready, log its output and delete it.
-func runKubernetesServiceTestContainer(c clientset.Interface, ns string) {
-	path := "test/images/clusterapi-tester/pod.yaml"
-	framework.Logf("Parsing pod from %v", path)
-	p, err := manifest.PodFromManifest(path)
-	if err != nil {
-		framework.Logf("Failed to parse clusterapi-tester from manifest %v: %v", path, err)
-		return
-	}
-	p.Namespace = ns
-	if _, err := c.CoreV1().Pods(ns).Create(p); err != nil {
-		framework.Logf("Failed to create %v: %v", p.Name, err)
-		return
-	}
-	defer func() {
-		if err := c.CoreV1().Pods(ns).Delete(p.Name, nil); err != nil {
-			framework.Logf("Failed to delete pod %v: %v", p.Name, err)
-		}
-	}()
-	timeout := 5 * time.Minute
-	if err := framework.WaitForPodCondition(c, ns, p.Name, "clusterapi-tester", timeout, testutils.PodRunningReady); err != nil {
-		framework.Logf("Pod %v took longer than %v to enter running/ready: %v", p.Name, timeout, err)
-		return
-	}
-	logs, err := framework.GetPodLogs(c, ns, p.Name, p.Spec.Containers[0].Name)
-	if err != nil {
-		framework.Logf("Failed to retrieve logs from %v: %v", p.Name, err)
-	} else {
-		framework.Logf("Output of clusterapi-tester:\n%v", logs)
-	}
+// ListE2ETests prints all tests as a driver/suite/pattern matrix
+// together with the predicted outcome, without running them.
+func ListE2ETests(t ginkgo.GinkgoTestingT, out io.Writer) {
+	gomega.RegisterFailHandler(ginkgowrapper.Fail)
+	config.GinkgoConfig.DryRun = true
+	// The framework enables verbose output, which would mix log
+	// messages with the list.
+	config.DefaultReporterConfig.Verbose = false
+	ginkgo.RunSpecsWithCustomReporters(t, "Kubernetes CSI E2E suite", []ginkgo.Reporter{storage.NewListReporter(out)})
 }
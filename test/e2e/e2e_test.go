@@ -17,34 +17,11 @@ limitations under the License.
 package e2e
 
 import (
-	"log"
 	"testing"
-
-	"k8s.io/kubernetes/test/e2e/framework"
-	"k8s.io/kubernetes/test/e2e/framework/testfiles"
-
-	. "github.com/onsi/ginkgo"
-
-	// test sources:
-	// this is our own package, not the one from
-	// k8s.io/kubernetes/test/e2e, but it uses the
-	// same underlying `testsuites` package to run
-	// the same tests against test drivers that we
-	// define.
-	_ "github.com/kubernetes-csi/csi-e2e/test/e2e/storage"
 )
 
 func init() {
-	log.SetOutput(GinkgoWriter)
-
-	// Register framework flags, then handle flags.
-	framework.HandleFlags()
-	framework.AfterReadingAllFlags(&framework.TestContext)
-
-	// TODO: do we really need extra files at runtime?
-	if framework.TestContext.RepoRoot != "" {
-		testfiles.AddFileSource(testfiles.RootFileSource{Root: framework.TestContext.RepoRoot})
-	}
+	Setup()
 }
 
 func TestE2E(t *testing.T) {
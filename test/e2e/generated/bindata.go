@@ -0,0 +1,458 @@
+// Code generated for package generated by go-bindata DO NOT EDIT. (@generated)
+// sources:
+// ../storage/manifests/external-attacher/rbac.yaml
+// ../storage/manifests/external-provisioner/rbac.yaml
+// ../storage/manifests/hostpath/example/usage/csi-storageclass.yaml
+// ../storage/manifests/hostpath/hostpath/csi-hostpath-attacher.yaml
+// ../storage/manifests/hostpath/hostpath/csi-hostpath-provisioner.yaml
+// ../storage/manifests/hostpath/hostpath/csi-hostpathplugin.yaml
+// ../storage/manifests/hostpath/usage/csi-storageclass.yaml
+// ../manifests/connectivity/pod.yaml
+// ../manifests/preflight/pod.yaml
+package generated
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func bindataRead(data []byte, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("Read %q: %v", name, err)
+	}
+
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, gz)
+	clErr := gz.Close()
+
+	if err != nil {
+		return nil, fmt.Errorf("Read %q: %v", name, err)
+	}
+	if clErr != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+type asset struct {
+	bytes []byte
+	info  os.FileInfo
+}
+
+type bindataFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// Name return file name
+func (fi bindataFileInfo) Name() string {
+	return fi.name
+}
+
+// Size return file size
+func (fi bindataFileInfo) Size() int64 {
+	return fi.size
+}
+
+// Mode return file mode
+func (fi bindataFileInfo) Mode() os.FileMode {
+	return fi.mode
+}
+
+// Mode return file modify time
+func (fi bindataFileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+// IsDir return file whether a directory
+func (fi bindataFileInfo) IsDir() bool {
+	return fi.mode&os.ModeDir != 0
+}
+
+// Sys return file is sys mode
+func (fi bindataFileInfo) Sys() interface{} {
+	return nil
+}
+
+var _testE2eStorageManifestsExternalAttacherRbacYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xcc\x54\x4d\x8b\x23\x37\x10\xbd\xeb\x57\x3c\xdc\x97\x04\xdc\x0e\x7b\x0b\xce\x69\x76\x0e\x61\x21\x81\x30\x1b\x06\x42\xd8\x83\x2c\x55\xbb\x2b\x96\xa5\x46\x55\xb2\x33\xfb\xeb\x83\xe4\x8f\x75\x76\xbc\x99\x99\x1d\x02\x7b\xb2\x69\x4a\xf5\xea\xbd\x7a\xaf\x3a\xfc\x3e\xb2\xe0\x8f\x9b\x5f\x7f\xc1\xc0\x81\xe0\x52\x54\xcb\x51\x60\x43\xc0\xdd\xdb\x9b\x5b\xa4\xd5\x5f\xe4\x54\xa0\xa3\x55\xd8\x4c\x88\xe4\x48\xc4\xe6\x07\x68\x42\x2e\x11\xf4\xb7\x52\x8e\x36\x98\x0e\xb7\xef\xdf\xc1\xaa\x5a\x37\x52\x5e\x98\xce\x74\x78\x17\x31\xe5\xe4\x8b\x53\x4e\x71\x0e\xb2\x6e\x6c\x55\x3e\xf3\x8e\x32\x3c\x4d\x21\x3d\x6c\x29\x2a\x46\x2b\xb5\xe3\x8a\xe0\x8a\x68\xda\xf2\x47\xf2\x4b\xd3\xa1\xaf\x5f\xed\x2e\xb1\xaf\xd3\x0d\x81\x9d\xca\x1c\x45\x08\x31\xc5\xde\xd3\x60\x4b\x50\x44\xbb\x25\x99\xac\x23\xd8\xe8\xe1\x79\x18\x28\xd7\xae\xed\xbb\xe9\x00\x0c\x29\xb7\x17\xe7\x4a\x0f\x8a\xca\xca\x24\x08\xbc\x21\xe8\x48\xb8\x0d\x45\x94\xf2\x5d\x0a\xd4\xa0\x3d\x39\xf6\x84\xfd\x48\x3a\x52\x6e\x25\x17\x23\x67\x9a\x02\x3b\xab\x54\xe5\xa1\xb3\x10\x95\x60\x83\x3c\x49\x31\x07\x47\xec\x47\x76\x23\x9c\x15\x42\x20\xeb\x29\xcb\xc8\x13\x28\x50\x93\x06\xdb\x22\x8a\x15\x81\xa2\x5d\x05\xf2\x3f\xb5\x06\x5a\xb7\xc3\x71\x08\x85\xa2\x3b\xa2\xb4\xad\x08\x69\x99\xe6\x10\x22\xac\x28\xa4\xbd\x31\x76\xe2\x7b\xca\xc2\x29\x2e\xb1\x7b\x63\x36\x1c\xfd\x12\xef\x29\xef\xd8\xd1\x8d\x73\xa9\x44\x35\x5b\x52\xeb\xad\xda\xa5\x41\x13\x6c\x09\x27\xdc\x9f\xa6\x34\x40\xd7\x28\x55\x15\xf7\xac\xe3\x17\x04\xae\x2f\x8f\x0d\x9a\x8e\x4b\x1c\x6b\x8c\xe9\xfb\xde\x74\xb8\x39\x36\x3c\x73\xaa\x8c\xea\x16\xf7\x29\x6f\x0e\x9d\x7f\xbb\x97\x39\x62\xf2\x24\x6d\x5f\xf7\x29\x94\x2d\x1d\xde\x55\x65\xe5\x38\xff\xe5\x3a\x2e\x09\xe6\x95\x75\x0b\x5b\x74\x4c\x99\x3f\xda\xaa\xdf\x62\xf3\xa3\x2c\x38\xfd\xb0\x7b\x73\x85\xe5\x69\x31\x67\xaa\x7d\x2e\x31\x52\x36\xb9\x04\x92\xaa\x46\x0f\x3b\xf1\xcf\x39\x95\x49\x96\xf8\x73\x36\xfb\x60\x00\x20\x93\xa4\x92\x1d\xb5\x6f\x53\x05\x17\xa5\xa8\xbb\x36\xad\x1c\x8b\x76\x94\x57\xad\x60\x4d\x3a\x9b\x63\x16\x58\xda\xef\xde\xaa\x1b\xeb\x9f\x32\x79\xab\x34\xfb\xf0\x3c\x98\x26\xca\xb3\x5a\x5f\x69\xe8\x84\x17\xa2\x29\xdb\x35\x1d\x05\xb9\x06\xe1\x84\x2b\x0a\xc7\x21\x7d\x35\xd2\xd3\x28\x07\x95\xec\xa7\x9d\xbe\x58\xaf\x66\xa7\x47\x46\x78\xcb\xd1\x73\x5c\xbf\xc6\x0f\x97\xae\xef\x73\x35\x97\x94\xc3\x99\xab\x35\x3d\xae\x86\x07\xb8\xf6\xbc\x51\x7a\x49\x6c\xae\x06\xa7\xce\x70\x47\x43\x45\x7f\x6c\xfc\x27\x5d\x8c\xf3\x6a\xfe\x43\x89\x17\x84\xb3\x9e\x59\x5e\x63\x6b\xa7\x7a\xb7\x5c\xc9\x9f\x2e\x69\x65\x62\x3a\xf0\x80\xef\x6a\x6e\x53\x0c\x0f\xe0\xe1\xfb\xab\x17\x8d\xe5\x74\xcc\x8e\x69\xfe\xfa\x18\xbf\xf2\x2e\x7d\x59\x41\x37\xac\x4f\x47\xe0\x33\x83\x37\xb7\xfe\x3b\x36\x4d\x96\xad\x9d\x0e\xa1\xf9\xdc\xc7\x67\xff\x9e\x0c\xed\x29\x90\xd2\x85\xa3\xe7\x98\xb9\x4c\x8f\xbc\xfd\x7f\x99\xba\x91\x7b\xb5\x76\xdf\x5a\x32\x9e\x88\xc4\x81\xf3\x73\xf2\xf0\xcf\x00\x18\x3d\x2c\xb8\xfc\x08\x00\x00")
+
+func testE2eStorageManifestsExternalAttacherRbacYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_testE2eStorageManifestsExternalAttacherRbacYaml,
+		"test/e2e/storage/manifests/external-attacher/rbac.yaml",
+	)
+}
+
+func testE2eStorageManifestsExternalAttacherRbacYaml() (*asset, error) {
+	bytes, err := testE2eStorageManifestsExternalAttacherRbacYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "test/e2e/storage/manifests/external-attacher/rbac.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _testE2eStorageManifestsExternalProvisionerRbacYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xcc\x55\xc1\x6e\x1b\x37\x10\xbd\xef\x57\x0c\xb4\x97\x16\xd0\xaa\xc8\xad\x50\x4f\x8e\x0f\x45\x80\x16\x28\x9c\xa2\x40\x51\xe4\x40\x91\x6f\xbd\x53\x53\xe4\x82\x33\x5c\xd5\xf9\xfa\x82\xf4\x5a\x91\x13\x59\xd9\xc4\x29\xd0\x9b\x30\x9a\x9d\x37\xef\xcd\xf0\x4d\x4b\xbf\x0f\x2c\xf4\xe7\xd5\xaf\xbf\x50\xcf\x1e\x64\x63\x50\xc3\x41\xc8\x78\x4f\x37\xaf\xaf\xae\x29\xee\xfe\x86\x55\x21\x1d\x8c\x92\x49\xa0\x00\x0b\x11\x93\xee\x49\x23\xa5\x1c\x08\xff\x28\x52\x30\xbe\x69\xe9\xfa\xed\x1b\x1a\x53\x9c\x58\x38\x06\xa4\x4d\xd3\x36\x2d\xbd\x09\x25\xe6\xb2\x55\x8e\x61\x4d\x30\x76\xa8\x89\x2e\xf1\x84\x44\x0e\xa3\x8f\xf7\x7b\x04\xa5\xc1\x48\x29\xba\x03\xd9\x2c\x1a\xf7\xfc\x1e\x6e\xdb\xb4\xd4\x95\xa8\x99\x22\xbb\xd2\x60\xef\xd9\xaa\xac\x29\x0b\x28\xc4\xd0\x39\xf4\x26\x7b\xa5\x60\xf6\x90\xd1\x58\x90\x09\x8e\x1c\xf7\x3d\x52\xa9\x5a\xe3\x4d\x4b\x44\x7d\x4c\xf5\x8b\x63\xa6\x23\x04\x65\x65\x08\x79\xbe\x03\xe9\x00\xba\xf6\x59\x14\xe9\x26\x7a\x54\x68\x07\xcb\x0e\x74\x18\xa0\x03\x52\x4d\x39\x69\x39\x61\xf4\x6c\x8d\xa2\x28\x84\xa3\x16\x85\x60\x85\x3c\x51\x63\x4d\x1c\xe8\x30\xb0\x1d\xc8\x1a\x01\x79\x18\x87\x24\x03\x8f\x04\x8f\xaa\x0e\xed\xb3\x28\xed\x40\x08\x66\xe7\xe1\x7e\xaa\x35\xb4\xcc\x88\x43\xef\x33\x82\x9d\x81\xea\x6c\x04\x9a\xc7\x35\x09\x40\x3b\xf8\x78\x68\x1a\x33\xf2\x1f\x48\x45\xfd\x2d\x4d\xaf\x9a\x3b\x0e\x6e\x4b\x6f\x91\x26\xb6\xb8\xb2\x36\xe6\xa0\xcd\x1e\x6a\x9c\x51\xb3\x6d\xa8\x6a\xb6\x25\x2b\xdc\x9d\x34\xda\x10\xb5\x95\x58\xd1\xf2\xc0\x3a\x3c\x23\x73\xf9\x78\xae\x51\xd5\xdc\xd2\x9c\xd3\x34\x5d\xd7\xcd\xe0\xa7\x72\x9e\x76\x97\x76\xc6\x6e\x4c\xd6\x21\x26\x7e\x6f\x0a\xf9\xcd\xdd\x8f\xb2\xe1\xf8\xc3\xf4\xea\x4c\x8b\x8f\xc2\x9e\xf6\xd9\xa5\x1c\x4a\xbb\x29\x7b\x48\x61\xd3\x91\x19\xf9\xe7\x14\xf3\x28\x5b\xfa\x6b\xb5\x7a\xd7\x10\x11\x25\x48\xcc\xc9\xa2\xc6\x04\x36\x41\x65\xfe\x6b\x42\xda\xd5\xf0\x2d\x74\xb5\xa6\x95\x67\xd1\xd5\xbb\x65\x95\xc6\xc2\x44\x14\x41\xa7\xe8\xf3\x1e\x17\x6b\xae\x69\x75\x30\x6a\x87\x12\xb0\x09\x46\x51\x7e\x39\x78\x28\xbe\x16\xd0\x7a\xc3\xfb\xc5\xa8\x79\x74\xe6\x3c\x96\x68\x4c\xe6\x16\xb3\xfc\xe7\x90\xe7\x0c\xeb\x8d\xc8\x42\x9e\x0b\x39\x61\x42\xf8\x74\x1a\x17\x24\x9b\x69\xac\x69\x35\x3e\x87\x23\xc1\x8c\x32\x44\xdd\x7c\x9e\xd8\x3c\xb9\xf9\x83\x8b\xcc\xbe\x25\x50\x71\x59\x84\x2f\xc6\xb3\xc2\x0b\xa0\xac\x70\x88\x0e\x1c\xfa\xf8\x4d\x47\x55\x8a\x2e\x2d\x78\xfe\xfd\xbf\xe6\xe0\x38\xdc\xbe\xc4\x06\x3e\x72\xaa\x2e\x15\x5b\x91\xfc\x70\xa3\x4a\x5a\x47\x67\x3d\x8f\xe8\x99\x0a\x75\x00\x5f\xe2\x76\x67\xfd\xae\xb4\x71\x83\xbe\x34\xf0\xa9\xeb\x2d\xb1\x30\x3a\xea\x7f\x41\x92\x07\x5b\x6d\xe9\xb7\x0f\xdf\x1f\x0f\x46\x39\x17\xe5\x4a\x1e\x62\xba\x7b\x60\x81\xe0\xc6\xc8\x41\xcb\xe9\x20\x9b\xd3\x87\x5b\x58\xe8\x34\x2d\x71\x4f\xdf\x95\x4b\x19\x83\xbf\x27\xee\xbf\x3f\x7b\x90\x58\x1e\x6f\xd1\x3c\xcf\xaf\x37\xf2\x17\xde\x94\x8b\x32\xda\xfe\xf6\xf1\x0c\x9c\x5b\xe5\x27\x8b\x7c\x14\xa6\x2e\xf3\xc7\xab\x7c\xf4\x9c\xc7\x9d\x9e\x4d\xfa\x89\xf7\xcc\x7e\xf4\x64\xd1\xff\xc3\x0d\xaf\xf4\x5e\x2c\xe0\xff\xf0\x99\x7c\xfe\x7d\x3c\x30\x5f\xf2\x38\xfe\x1d\x00\x96\xf0\x45\x09\xcc\x0a\x00\x00")
+
+func testE2eStorageManifestsExternalProvisionerRbacYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_testE2eStorageManifestsExternalProvisionerRbacYaml,
+		"test/e2e/storage/manifests/external-provisioner/rbac.yaml",
+	)
+}
+
+func testE2eStorageManifestsExternalProvisionerRbacYaml() (*asset, error) {
+	bytes, err := testE2eStorageManifestsExternalProvisionerRbacYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "test/e2e/storage/manifests/external-provisioner/rbac.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _testE2eStorageManifestsHostpathExampleUsageCsiStorageclassYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x54\xcc\xb1\x8a\xc3\x30\x0c\x87\xf1\x5d\x4f\xa1\x17\x48\x8e\xdb\x0e\x8f\x77\xb7\x74\x28\x14\x0a\xdd\x85\xfd\x27\x11\xb1\xad\x60\xa9\x81\xbe\x7d\x29\x9d\xba\x7e\x1f\xfc\x64\xd7\x1b\x86\xab\xf5\xc4\x1e\x36\x64\xc1\xbc\xfd\xf8\xac\xf6\x75\x7c\xd3\xa6\xbd\x24\xbe\xbe\xfb\x5f\x15\x77\x6a\x08\x29\x12\x92\x88\xb9\x4b\x43\xe2\xec\x3a\xad\xe6\xb1\x4b\xac\x93\x67\xda\x87\x1d\xfa\x12\x31\x3e\x27\x0d\xe4\x2a\xda\x2e\x56\x35\x3f\x12\xff\xa3\x22\x40\x87\xd5\x7b\xc3\xaf\xf6\xa2\x7d\x39\x5b\x41\xe2\x53\x6b\x28\x2a\x01\x7a\x0e\x00\x20\x58\xb7\x12\xa0\x00\x00\x00")
+
+func testE2eStorageManifestsHostpathExampleUsageCsiStorageclassYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_testE2eStorageManifestsHostpathExampleUsageCsiStorageclassYaml,
+		"test/e2e/storage/manifests/hostpath/example/usage/csi-storageclass.yaml",
+	)
+}
+
+func testE2eStorageManifestsHostpathExampleUsageCsiStorageclassYaml() (*asset, error) {
+	bytes, err := testE2eStorageManifestsHostpathExampleUsageCsiStorageclassYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "test/e2e/storage/manifests/hostpath/example/usage/csi-storageclass.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _testE2eStorageManifestsHostpathHostpathCsiHostpathAttacherYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x92\x41\x8f\xd3\x30\x10\x85\xef\xf9\x15\xa3\x15\x07\x38\xb8\xa6\xc0\x4a\xc8\xd2\x1e\x2a\xca\x0d\x96\x8a\x4a\xdc\xa7\xce\xd0\x58\xb5\x63\x63\x8f\x83\xf2\xef\x91\xd3\xb4\x38\xec\x42\x85\xe4\xd3\xf8\x9b\xe7\xf7\xc6\x73\x32\x7d\xab\x60\x4f\x71\x30\x9a\x1a\x0c\xe6\x1b\xc5\x64\x7c\xaf\x60\x58\x37\x8e\x18\x5b\x64\x54\x0d\x40\x8f\x8e\x14\xe8\x64\x44\xe7\x13\x07\xe4\x4e\x20\x33\xea\x8e\x62\x03\x60\xf1\x40\x36\x15\x0e\x00\x43\xf8\x1b\x98\x02\xe9\x02\x25\xb2\xa4\xd9\xc7\x9b\x0d\x00\xc1\x47\x9e\x85\xc5\x6c\xa2\xcd\xce\x8d\x53\xe5\x7c\xad\x60\xfd\xe6\xed\xbb\xfb\xa6\x11\x42\x34\x73\x20\x46\xa6\xef\xd9\xee\x89\x17\xa1\x30\x84\x24\xff\x23\xd9\x6f\xc3\xd3\x80\x1e\x27\xf4\xee\x59\xf6\xae\x01\x88\x14\xac\xd1\x98\x14\xac\x9f\xa4\x74\xc8\xba\xfb\x54\xcd\xe9\x46\x70\x26\x17\x2c\x32\xcd\xdd\x95\x61\x80\xe5\xc0\x6f\x4a\x01\x5c\x72\x00\x5c\xb3\x6c\xb4\xf6\xb9\xe7\xc7\x6b\xfa\x45\x03\x80\xf6\x3d\xa3\xe9\x29\x56\xaf\x5c\x7e\xe0\x19\xbc\x1c\xe3\xf0\x48\x0a\x7e\x64\x1c\x57\xc6\xcb\xd3\xfb\xa4\x93\x91\x35\xac\x86\xf5\xea\xf5\x6a\x5d\xf5\x60\x3c\x56\x0f\x94\x23\x40\x88\xe1\xe1\xfe\x49\x6d\xd2\x69\xdb\x48\x29\x3d\xbc\x78\xb9\xd9\x6e\xbf\x7e\xdc\xef\x5f\x55\x18\xf5\xc3\x9f\x4a\x67\xbb\x33\xbb\xb8\x03\x18\xd0\x66\x52\x20\x67\x8f\xab\xe4\xf5\xa9\x42\xa6\x30\xbb\x6c\xed\xce\x5b\xa3\x47\x05\x1b\xfb\x13\xc7\x54\x11\x83\xb7\xd9\xd1\xe7\x32\xc5\x45\x04\x01\xae\xd4\x76\xc8\xdd\x59\xbe\xba\xbb\xac\x5b\x79\x8c\x58\xb4\xe6\x32\xbf\xb3\x58\xa5\x23\xa0\xac\xd8\x24\x72\xad\x95\x53\x96\x4e\x81\x1c\x30\x4a\x6b\x0e\xf2\x94\x0f\x64\x89\x65\xb0\xf9\x68\xfa\x24\xeb\x1d\x58\xf4\xf1\x18\x48\xc1\xd6\xc4\x69\x27\xc7\x2f\xf1\x43\x24\x64\x6a\xfe\xe1\xec\xd7\x00\xd6\x6e\xfa\x07\x1d\x04\x00\x00")
+
+func testE2eStorageManifestsHostpathHostpathCsiHostpathAttacherYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_testE2eStorageManifestsHostpathHostpathCsiHostpathAttacherYaml,
+		"test/e2e/storage/manifests/hostpath/hostpath/csi-hostpath-attacher.yaml",
+	)
+}
+
+func testE2eStorageManifestsHostpathHostpathCsiHostpathAttacherYaml() (*asset, error) {
+	bytes, err := testE2eStorageManifestsHostpathHostpathCsiHostpathAttacherYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "test/e2e/storage/manifests/hostpath/hostpath/csi-hostpath-attacher.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _testE2eStorageManifestsHostpathHostpathCsiHostpathProvisionerYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x92\xcf\x8e\xd3\x30\x10\xc6\xef\x79\x8a\x51\xc5\x01\x0e\x6e\x08\xb0\x12\xb2\xd4\x43\x45\xb9\xc1\x52\x51\x89\xfb\xd4\x19\x5a\xab\x8e\x6d\xec\x71\x50\xde\x1e\x39\x4d\x2b\xa7\x5d\x96\x45\xc9\x69\xe6\x37\x7f\xbe\x6f\x7c\xd2\xb6\x95\xb0\xa3\xd0\x6b\x45\x15\x7a\xfd\x83\x42\xd4\xce\x4a\xe8\x9b\xaa\x23\xc6\x16\x19\x65\x05\x60\xb1\x23\x09\x2a\x6a\x71\x74\x91\x3d\xf2\x51\xf8\xe0\x7a\x9d\x61\x0a\x50\x01\x18\xdc\x93\x89\x99\x05\x40\xef\x9f\x83\xa3\x27\x95\xc1\x48\x86\x14\xbb\xf0\xa2\x22\x00\xef\x02\x4f\x03\xc4\xb4\x50\x9b\xba\x6e\x18\x23\xe7\xb4\x84\xe6\xdd\xfb\x0f\x0f\x55\x25\x84\xa8\x26\x71\x8c\x4c\x3f\x93\xd9\x11\xcf\x04\xa2\xf7\xb1\xfe\x2f\x95\xc5\xde\xa3\x5f\x8f\x23\xbd\xf8\x1b\xbe\xa8\x00\x02\x79\xa3\x15\x46\x09\xcd\x9d\xde\x0e\x59\x1d\xbf\x14\xae\xfd\xc3\x82\x0a\x80\xa9\xf3\x06\x99\xa6\x06\xc5\xe6\x00\xf3\x0b\xbc\xa4\x1b\xc0\x45\x10\xc0\x55\xd4\x5a\x29\x97\x2c\x3f\x5e\x9d\xb8\xad\x01\x50\xce\x32\x6a\x4b\xa1\x98\x75\x39\xc8\xd3\x15\xf9\xd3\x1d\x1e\x48\xc2\xaf\x84\xc3\x52\xbb\xfa\xf4\x31\xaa\xa8\xeb\x1b\x5e\xf6\xcd\xf2\xed\xb2\x29\xca\x30\x1c\x8a\x31\xf9\x17\xb0\x10\x65\xcd\xaa\x94\xb8\xb8\x47\x73\x1a\xdb\x36\x50\x8c\xab\x57\xaf\xd7\x9b\xcd\xf7\xcf\xbb\xdd\x9b\xa7\x40\x67\x2d\x29\xd6\xce\x0a\xd6\x1d\xb9\xc4\xab\xe6\x21\x96\x20\xd9\xfe\x76\x97\xb3\xec\xa9\xeb\x2c\x07\xd0\xa3\x49\x24\xa1\x9e\x84\x2e\xa3\x53\xa7\x02\x19\x1d\xd9\x26\x63\xb6\xce\x68\x35\x48\x58\x9b\xdf\x38\xc4\x82\xe8\x9d\x49\x1d\x7d\xcd\x07\xb9\x33\xa1\xcb\xd1\x2d\xf2\xf1\x3c\x60\x96\xbd\xbc\xe4\x3c\x90\x58\xb4\xfa\x72\x88\x73\xc3\xa2\x97\x80\xec\xdc\xd8\xe6\x1a\xcb\x7f\xf6\x52\x42\xdd\x63\xa8\x8d\xde\xd7\xa7\xb4\x27\x43\x5c\x7b\x93\x0e\xda\xc6\xba\xb4\x7c\x56\xc7\x83\x27\x09\x1b\x1d\xc6\x87\x3e\x7c\x0b\x9f\x02\x21\x53\xf5\xcc\x66\x7f\x06\x00\x08\x96\xf8\xa9\x84\x04\x00\x00")
+
+func testE2eStorageManifestsHostpathHostpathCsiHostpathProvisionerYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_testE2eStorageManifestsHostpathHostpathCsiHostpathProvisionerYaml,
+		"test/e2e/storage/manifests/hostpath/hostpath/csi-hostpath-provisioner.yaml",
+	)
+}
+
+func testE2eStorageManifestsHostpathHostpathCsiHostpathProvisionerYaml() (*asset, error) {
+	bytes, err := testE2eStorageManifestsHostpathHostpathCsiHostpathProvisionerYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "test/e2e/storage/manifests/hostpath/hostpath/csi-hostpath-provisioner.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _testE2eStorageManifestsHostpathHostpathCsiHostpathpluginYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd4\x54\xc1\x6e\xdb\x30\x0c\xbd\xe7\x2b\x88\xa2\x87\xf6\xe0\x78\x19\x30\x60\x10\x90\x43\xdb\x64\x40\xb1\x35\x0d\xd6\x6d\xd7\x80\xb5\xd8\x44\x88\x2c\x79\x12\xed\xd6\x7f\x3f\xc8\x4e\x0a\xd9\x71\x93\x6e\xb7\x41\x3e\xd8\xf4\xe3\x23\xf5\xf8\xc0\xad\x32\x52\xc0\x0c\x29\xb7\xe6\x81\x78\x84\x85\xfa\x45\xce\x2b\x6b\x04\x60\x51\xf8\xb4\x9a\x8c\x72\x62\x94\xc8\x28\x46\x00\x06\x73\x12\x90\x79\x95\x6c\xac\xe7\x02\x79\x53\xe8\x72\xad\xcc\xc8\x17\x94\x05\x80\x27\x4d\x19\x5b\x17\xde\x01\x72\xe4\x6c\xf3\x0d\x1f\x49\xfb\x36\x00\x81\x75\x90\x00\x80\x29\x2f\x34\x32\xed\x52\xa3\xaa\xe1\x5b\x77\x58\x8e\xf1\x00\xec\x9b\x09\x27\x94\x59\x10\x3f\x5b\xb7\x15\xc0\xae\xa4\x5d\x3c\xb3\x86\x51\x19\x72\x11\x67\xb2\xbb\x9f\x74\xaa\x22\x97\x38\x5a\x2b\xcf\x0e\xdd\x2b\x00\x40\xe5\xb8\x26\x01\xbf\x4b\xac\xc7\xca\xa6\xdb\xcf\x3e\xf3\x2a\x0d\xf7\x31\x56\x52\xd2\xcf\x14\xd5\x64\xfc\x61\xfc\x31\x22\x40\xb7\x8e\x2a\x86\x27\x81\x24\xa9\xa6\x9f\x0e\x62\x81\x14\xa5\x74\xe4\xfd\x34\xdd\x55\x19\x7b\x9b\x6d\x0f\x90\xdb\xf2\x91\x34\xf1\x6b\x59\x56\xd6\x24\x41\xdb\x69\x5a\xa1\x4b\xb5\x7a\x4c\x77\x90\xb4\x55\xdb\xa7\xb1\x72\x43\xc4\x64\xaa\x7e\x97\xad\x36\x5f\x7f\x5e\xcf\x57\x8b\xfb\xd9\x7c\xb5\xb8\xba\x9b\x77\x20\x00\x15\xea\x92\xbe\x38\x9b\x77\x73\xc3\x79\x52\xa4\xe5\x77\x7a\x3a\xfc\x03\x10\xbb\xae\x9a\x0c\x00\x9a\xe4\x25\xf2\x46\x34\xb3\x1d\x07\xad\x17\x98\x53\x7f\x30\xcb\x52\xeb\xa5\xd5\x2a\xab\x05\x5c\xe9\x67\xac\x7d\x84\xa8\xac\x2e\x73\xba\xb3\xa5\xe1\xce\x04\x12\xc8\x43\xac\xa5\x0f\x5a\x44\xff\xf6\x8e\x0f\xb2\x13\x27\x52\xb9\x37\x13\x63\xf1\x07\x18\x3a\xb3\x89\x79\xf6\xc2\xee\xa7\x71\xd2\x6c\x7b\x60\x3b\xca\xd6\x61\x93\x13\x0e\x3b\x6b\x2c\x76\x76\x18\x25\x23\x0b\xab\x0c\x4f\xcf\x2f\x6e\x1e\x6e\x57\xf3\xc5\x6c\x79\x7f\xbb\xf8\x71\x39\x00\x0d\xa2\x2b\x39\x3d\xbf\xe8\x5a\xe0\xf2\xec\x3d\xb6\x89\xc9\x87\x4c\x23\xa0\x34\xea\x45\xa4\xe9\x31\xa7\xff\xef\x0e\xf4\x94\x95\x4e\x71\x7d\x63\x0d\xd3\x0b\x77\x1b\x29\x9c\xaa\x94\xa6\x35\xc9\xce\x9e\x3a\xe6\xdc\x53\xde\x3d\xea\xde\x7e\xf2\xc1\xa6\xb0\x32\xee\x3d\x9c\x16\xee\x6c\x81\xeb\xc6\xc7\x02\xae\x95\x54\x8e\xb2\xf0\x81\xba\x87\x6e\x6b\x37\x39\x8d\xc5\xa2\xfa\xed\x85\xa2\xbb\x24\x10\x5c\xdd\xe8\xfb\x1a\x0b\x4f\xf1\x46\x6f\x03\x5b\xac\x93\xc7\x75\x41\x02\x66\x4d\x6f\xd6\xd5\xf7\xee\xc6\x11\x32\x8d\xde\xa1\xcc\xdf\xb6\xd2\x97\xe9\xbd\xa5\x07\x85\xf9\x57\x25\x56\xbb\xf5\x52\x1f\x6b\x65\x74\x72\x27\xfd\x19\x00\xb7\x5a\xf9\x02\x0f\x08\x00\x00")
+
+func testE2eStorageManifestsHostpathHostpathCsiHostpathpluginYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_testE2eStorageManifestsHostpathHostpathCsiHostpathpluginYaml,
+		"test/e2e/storage/manifests/hostpath/hostpath/csi-hostpathplugin.yaml",
+	)
+}
+
+func testE2eStorageManifestsHostpathHostpathCsiHostpathpluginYaml() (*asset, error) {
+	bytes, err := testE2eStorageManifestsHostpathHostpathCsiHostpathpluginYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "test/e2e/storage/manifests/hostpath/hostpath/csi-hostpathplugin.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _testE2eStorageManifestsHostpathUsageCsiStorageclassYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x54\xcc\xb1\x8a\xc3\x30\x0c\x87\xf1\x5d\x4f\xa1\x17\x48\x8e\xdb\x0e\x8f\x77\xb7\x74\x28\x14\x0a\xdd\x85\xfd\x27\x11\xb1\xad\x60\xa9\x81\xbe\x7d\x29\x9d\xba\x7e\x1f\xfc\x64\xd7\x1b\x86\xab\xf5\xc4\x1e\x36\x64\xc1\xbc\xfd\xf8\xac\xf6\x75\x7c\xd3\xa6\xbd\x24\xbe\xbe\xfb\x5f\x15\x77\x6a\x08\x29\x12\x92\x88\xb9\x4b\x43\xe2\xec\x3a\xad\xe6\xb1\x4b\xac\x93\x67\xda\x87\x1d\xfa\x12\x31\x3e\x27\x0d\xe4\x2a\xda\x2e\x56\x35\x3f\x12\xff\xa3\x22\x40\x87\xd5\x7b\xc3\xaf\xf6\xa2\x7d\x39\x5b\x41\xe2\x53\x6b\x28\x2a\x01\x7a\x0e\x00\x20\x58\xb7\x12\xa0\x00\x00\x00")
+
+func testE2eStorageManifestsHostpathUsageCsiStorageclassYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_testE2eStorageManifestsHostpathUsageCsiStorageclassYaml,
+		"test/e2e/storage/manifests/hostpath/usage/csi-storageclass.yaml",
+	)
+}
+
+func testE2eStorageManifestsHostpathUsageCsiStorageclassYaml() (*asset, error) {
+	bytes, err := testE2eStorageManifestsHostpathUsageCsiStorageclassYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "test/e2e/storage/manifests/hostpath/usage/csi-storageclass.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _testE2eManifestsConnectivityPodYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa4\x53\xd1\x8a\x1b\x47\x10\x7c\xdf\xaf\x28\xb4\x47\x48\xe0\x24\x63\x43\x5e\x36\xce\x41\x62\x0b\x72\x04\xe4\xe3\xa4\xf8\xd5\xb4\x66\x7a\xb5\x83\x56\xdd\xcb\xf4\xac\x2e\x17\xf2\xf1\x61\x46\xeb\x8d\x02\xf6\xe5\xc1\x4f\xd2\x74\x57\xf5\x54\xd5\xf4\xd6\xd8\x75\xc1\x30\xa8\x87\xeb\xd8\x1d\x0d\x4f\x1d\xa7\x8e\x23\xde\x6f\xb6\x20\xf1\x48\x1d\xe3\x97\x87\x7b\x18\xc7\x33\x47\x50\x64\x44\x26\xd7\xd1\xbe\x67\xb4\x51\x4f\x55\x0d\x82\xa8\xe7\x5b\x90\x41\x98\x3d\x7b\xec\x9f\x0b\xf1\xdd\xf6\x1e\x16\x3c\x3b\x8a\xb6\xc2\xae\x63\xac\xdf\xac\x61\x63\x48\x8c\x38\x8a\x41\x85\xab\x1a\x41\x2c\x91\x38\xc6\xc0\xb1\x8c\xc2\x9e\x5b\x8d\x0c\x4b\x14\x53\x90\x03\x12\x5b\xb2\x5b\x68\x06\x05\x29\xb3\xb3\x66\xe1\xf4\xa4\xf1\x98\x25\x88\xff\x4f\xb7\x53\x4b\x9f\xdb\xe8\xc3\x91\xe7\xea\x40\xa9\x83\x8f\xe1\xcc\xf1\x16\x4f\x21\x75\x3a\xa6\xdc\xac\xea\xe2\xb9\xc4\x80\x3d\x3b\x1a\x8d\x61\xa3\xeb\x72\x3a\x86\x7c\xca\x23\x0a\x46\xa5\x0d\x87\x31\x52\x0a\x2a\xd0\x36\xd3\x8b\xee\x55\x55\xe3\x3e\x21\xf2\xd0\x93\x63\x2b\xf5\x70\xa2\x03\x5b\xb9\xa9\x9c\xbd\xd8\x98\x42\x6f\x45\xf2\x7e\xb4\xe7\xbd\xfe\x39\x81\xaa\x7a\x1e\xcd\x1e\xad\xc6\x42\xc8\xde\x73\x5a\x97\x00\x67\x7a\xa1\x20\x14\x65\x17\xf0\xfb\xcd\xb6\xaa\x67\xe9\x99\x2a\xd6\xab\x1e\xc7\x21\x6b\x8c\xec\x58\xd2\x7c\xe3\x99\xa3\x05\x15\x83\x57\x36\x88\x66\xd5\xa6\xfd\x39\xbf\x87\xd0\xa9\x88\x8f\x3a\x1e\xba\xa2\xc1\x98\xa2\xeb\xe0\xf5\x44\x41\x6c\x55\xd5\x55\x8d\x35\xb9\xee\xb2\x35\x18\x62\x90\x54\x5e\x13\x7d\x10\xbe\x98\x5d\xbc\x2d\xcd\xbb\x06\x7a\x5c\x40\x63\x55\x5f\x95\x5a\x0a\x3d\xfb\x06\x6f\x23\x93\xa9\xdc\x2d\x56\x15\x0d\xe1\xe3\x45\x54\x83\xf3\xeb\xea\x18\xc4\x37\x78\x50\x5f\x9d\x38\x91\xa7\x44\x4d\x05\x1c\x58\x38\x52\xe2\x0d\x9d\xb8\x81\xb3\xb0\x74\x2a\xc2\x2e\x85\x73\x48\xcf\xcb\x72\xe3\xb2\xb2\x81\x5d\x46\x47\x2e\x1b\xf4\xa0\x7d\x70\xcf\x0d\x36\x7c\xe6\x58\x21\x87\x9c\x28\x08\x47\xcb\xa0\x65\xf1\xdb\xc0\x8b\x55\x00\x2e\x6f\xd1\xcc\x41\x97\x9a\xd3\xd3\x89\xc4\x67\x78\x26\xbc\xda\x07\x79\x65\xdd\x74\x5a\xba\xe9\xcf\xdf\xe5\x17\x08\x2d\x74\x4c\x3f\xdf\x7c\x3f\xe7\x7f\x1c\xf7\x1c\x85\x13\xdb\xca\x73\x4b\x63\x9f\xf0\xe6\xee\xbb\xd7\x3f\xfc\x94\xd3\x95\x89\x06\xb0\xeb\x14\x0b\x2f\x56\x32\x9b\xca\xdc\x1b\x7f\x09\x31\x45\xb8\xc0\x8d\x8e\x69\x06\xb4\xf4\x2f\xbc\x0d\x57\xf6\x68\x08\x97\x6f\xf8\xda\xe4\xb4\x0d\xdf\xe4\xd1\x61\xf9\x17\x96\x4f\xf8\x11\x8b\x9b\xdf\xff\xf8\x75\xfd\xb8\x59\xef\xd6\xdb\x4f\xdb\xf5\xe3\xc7\xfb\x77\xeb\x4f\xbf\x7d\xd8\xee\x16\x5f\x6e\x3d\x7c\x78\xdc\x2d\x5e\x08\x62\x96\xfc\x3f\x71\x5c\xe1\x3e\xef\x95\x23\xc9\x3b\x3d\x2d\x07\x92\xe2\x6b\xda\x9a\xaf\x2b\x7b\x29\xd8\x7f\x06\x00\xa2\x65\xbb\xd0\x3a\x05\x00\x00")
+
+func testE2eManifestsConnectivityPodYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_testE2eManifestsConnectivityPodYaml,
+		"test/e2e/manifests/connectivity/pod.yaml",
+	)
+}
+
+func testE2eManifestsConnectivityPodYaml() (*asset, error) {
+	bytes, err := testE2eManifestsConnectivityPodYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "test/e2e/manifests/connectivity/pod.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _testE2eManifestsPreflightPodYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x84\x53\xcd\x8e\xdb\x36\x10\xbe\xeb\x29\x3e\xd8\x01\xf6\x12\xaf\xd1\x02\xbd\xa8\xd9\x1c\x9a\xee\x21\x87\x06\x0b\x34\xe8\xb5\xa0\xc9\x91\x34\x30\xc5\x61\x66\x28\xef\x1a\xc8\xc3\x17\x94\xe4\x38\x3f\xdb\x2c\x7c\x11\xc7\xdf\xdf\xcc\x90\x5b\x7c\x1c\xd8\x90\x25\xc0\x0f\xe4\x8f\x86\xc7\x81\xca\x40\x0a\x87\x24\x81\x30\x12\x15\x43\x19\x08\x59\x49\xe9\xd3\xc4\xc6\x85\x0c\x9d\x68\xb3\x45\xa0\x1c\xe5\xcc\xa9\xc7\xbb\xbf\xdf\x23\x28\x9f\x48\xed\x16\xef\x0b\x12\x51\x58\x78\xe6\xc6\x4a\xe6\x13\x47\xea\xc9\xe0\x0c\x6e\x85\x36\xdb\xea\xfc\x1a\x26\xf0\x4a\xae\x54\x21\x2e\x70\x51\xc9\x85\x33\x3a\xc7\x71\x0e\x94\xae\xfc\x50\x19\x06\xa7\x84\x24\xa5\xd9\xc2\xc5\x28\x8f\x14\x6e\xf1\x71\x20\xdc\xff\x7a\x0f\x9b\xb8\x10\x74\x4a\x06\x49\x04\x4e\x56\x5c\xf2\x84\x4c\xba\xb4\x74\xa0\x4e\x94\x60\xc5\x69\x75\x6c\xb6\x28\x64\xc5\xe0\x52\x80\x52\x8e\xce\xd3\x92\x9c\x47\xd7\x13\x1e\xb9\x0c\xf3\xf1\x30\xd9\xf9\x20\x4f\x6b\xd9\x4b\xea\xb8\x9f\x94\xc2\x3a\x8b\x0a\xa9\x42\xd5\xfa\xb6\xd9\x36\x5b\xdc\x3b\x3f\x2c\x63\xad\xf9\x53\x59\x02\x45\x4e\xab\xe8\xe6\xcd\xfc\xe7\xdb\x16\x72\xdc\x60\x16\xb9\x96\x6a\xf3\x14\x5a\xbc\x51\x72\x26\xe9\xed\xe6\xb6\x71\x99\xff\x21\x35\x96\xd4\xe2\xf4\x4b\x73\xe4\x14\x5a\x3c\x48\x68\x46\x2a\x2e\xb8\xe2\xda\x06\xe8\x29\x91\xba\x42\x1f\xdc\x48\x2d\xbc\xf1\x2e\x2b\x75\x91\xfb\xa1\xec\x1a\xcb\xe4\x2b\x48\x69\xee\xfe\x41\x22\xfb\x73\x8b\x0f\x54\x97\x01\x78\x49\xc5\x71\x22\xb5\x0a\xda\x21\xcd\x1a\x59\xe5\x40\x0d\x80\xa5\xf3\xf6\x32\x88\xb9\x64\xe4\x27\xe5\x72\x7e\x27\xa9\xd0\x53\xa9\xbc\xfa\xbb\xee\xab\x45\xd1\x69\xa1\x7b\x19\x47\x97\xc2\x82\xd9\x61\x7f\xe0\xb4\xb7\x61\x3d\xed\xfc\xfa\xf1\x79\x95\x90\xe3\xdd\x17\x26\xc0\xdd\xbc\x25\xec\x02\xf6\x27\xa7\xfb\xc8\x87\xfd\x71\x3a\x50\xa4\xb2\xcf\x71\xea\x39\xd9\xbf\x4a\x3d\x5b\xd1\xf3\xef\x75\x5d\x69\x25\x02\xe4\x07\xc1\x66\x05\xe1\x02\x9a\x87\xbe\x62\x28\x1a\xbd\x08\xbf\x2c\xe4\x45\x7b\x04\x21\xab\xb7\x13\xf4\xc4\x56\x5e\x63\x05\x5e\xeb\x36\xe5\x2c\x5a\xb0\x98\x5c\x3c\x5c\x61\x49\x9b\x2f\x39\xe4\x78\xd7\xb9\x6b\xb0\x8e\xd7\x0f\xee\xe0\x1e\x8f\xb8\x79\xf5\x1b\xee\xee\xb0\xf9\x3e\xce\xe6\x06\xfb\xac\xe2\xf7\x46\xb1\xdb\x8f\x32\xa5\xc2\xa9\x13\x7c\x46\xaf\x94\xb1\xfb\x84\x1b\x1b\x9c\x52\x68\x6f\x9e\x9d\xd3\xcc\x40\x56\xc9\xae\x9f\x13\xbd\x30\xa8\x67\xf0\xff\x37\x29\xf0\xd2\xfe\x4c\xa1\xb0\x3c\x82\x03\x07\x56\xf2\xd5\xc9\x45\xfc\xa0\xf6\xf2\x3c\x5e\xc9\x71\x2e\x9d\x24\x4e\x23\xfd\x55\x05\xec\x72\xc5\x96\x0b\xbc\xda\xef\x02\xeb\xca\x99\x6d\x1e\x5c\x19\x7e\x0c\xf9\x0d\xe2\xeb\xae\xfe\xf8\x3a\x68\x73\xf1\xfb\xe6\xa5\x7c\x6f\x34\x88\x2d\x2e\xab\x68\xfe\x99\x63\x39\x67\x6a\xf1\x27\x2b\xf9\x22\x7a\x6e\xfe\x1b\x00\x35\x38\xc6\x52\x9b\x05\x00\x00")
+
+func testE2eManifestsPreflightPodYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_testE2eManifestsPreflightPodYaml,
+		"test/e2e/manifests/preflight/pod.yaml",
+	)
+}
+
+func testE2eManifestsPreflightPodYaml() (*asset, error) {
+	bytes, err := testE2eManifestsPreflightPodYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "test/e2e/manifests/preflight/pod.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+// Asset loads and returns the asset for the given name.
+// It returns an error if the asset could not be found or
+// could not be loaded.
+func Asset(name string) ([]byte, error) {
+	cannonicalName := strings.Replace(name, "\\", "/", -1)
+	if f, ok := _bindata[cannonicalName]; ok {
+		a, err := f()
+		if err != nil {
+			return nil, fmt.Errorf("Asset %s can't read by error: %v", name, err)
+		}
+		return a.bytes, nil
+	}
+	return nil, fmt.Errorf("Asset %s not found", name)
+}
+
+// MustAsset is like Asset but panics when Asset would return an error.
+// It simplifies safe initialization of global variables.
+func MustAsset(name string) []byte {
+	a, err := Asset(name)
+	if err != nil {
+		panic("asset: Asset(" + name + "): " + err.Error())
+	}
+
+	return a
+}
+
+// AssetInfo loads and returns the asset info for the given name.
+// It returns an error if the asset could not be found or
+// could not be loaded.
+func AssetInfo(name string) (os.FileInfo, error) {
+	cannonicalName := strings.Replace(name, "\\", "/", -1)
+	if f, ok := _bindata[cannonicalName]; ok {
+		a, err := f()
+		if err != nil {
+			return nil, fmt.Errorf("AssetInfo %s can't read by error: %v", name, err)
+		}
+		return a.info, nil
+	}
+	return nil, fmt.Errorf("AssetInfo %s not found", name)
+}
+
+// AssetNames returns the names of the assets.
+func AssetNames() []string {
+	names := make([]string, 0, len(_bindata))
+	for name := range _bindata {
+		names = append(names, name)
+	}
+	return names
+}
+
+// _bindata is a table, holding each asset generator, mapped to its name.
+var _bindata = map[string]func() (*asset, error){
+	"test/e2e/storage/manifests/external-attacher/rbac.yaml":                     testE2eStorageManifestsExternalAttacherRbacYaml,
+	"test/e2e/storage/manifests/external-provisioner/rbac.yaml":                  testE2eStorageManifestsExternalProvisionerRbacYaml,
+	"test/e2e/storage/manifests/hostpath/example/usage/csi-storageclass.yaml":    testE2eStorageManifestsHostpathExampleUsageCsiStorageclassYaml,
+	"test/e2e/storage/manifests/hostpath/hostpath/csi-hostpath-attacher.yaml":    testE2eStorageManifestsHostpathHostpathCsiHostpathAttacherYaml,
+	"test/e2e/storage/manifests/hostpath/hostpath/csi-hostpath-provisioner.yaml": testE2eStorageManifestsHostpathHostpathCsiHostpathProvisionerYaml,
+	"test/e2e/storage/manifests/hostpath/hostpath/csi-hostpathplugin.yaml":       testE2eStorageManifestsHostpathHostpathCsiHostpathpluginYaml,
+	"test/e2e/storage/manifests/hostpath/usage/csi-storageclass.yaml":            testE2eStorageManifestsHostpathUsageCsiStorageclassYaml,
+	"test/e2e/manifests/connectivity/pod.yaml":                                   testE2eManifestsConnectivityPodYaml,
+	"test/e2e/manifests/preflight/pod.yaml":                                      testE2eManifestsPreflightPodYaml,
+}
+
+// AssetDir returns the file names below a certain
+// directory embedded in the file by go-bindata.
+// For example if you run go-bindata on data/... and data contains the
+// following hierarchy:
+//
+//	data/
+//	  foo.txt
+//	  img/
+//	    a.png
+//	    b.png
+//
+// then AssetDir("data") would return []string{"foo.txt", "img"}
+// AssetDir("data/img") would return []string{"a.png", "b.png"}
+// AssetDir("foo.txt") and AssetDir("notexist") would return an error
+// AssetDir("") will return []string{"data"}.
+func AssetDir(name string) ([]string, error) {
+	node := _bintree
+	if len(name) != 0 {
+		cannonicalName := strings.Replace(name, "\\", "/", -1)
+		pathList := strings.Split(cannonicalName, "/")
+		for _, p := range pathList {
+			node = node.Children[p]
+			if node == nil {
+				return nil, fmt.Errorf("Asset %s not found", name)
+			}
+		}
+	}
+	if node.Func != nil {
+		return nil, fmt.Errorf("Asset %s not found", name)
+	}
+	rv := make([]string, 0, len(node.Children))
+	for childName := range node.Children {
+		rv = append(rv, childName)
+	}
+	return rv, nil
+}
+
+type bintree struct {
+	Func     func() (*asset, error)
+	Children map[string]*bintree
+}
+
+var _bintree = &bintree{nil, map[string]*bintree{
+	"test": &bintree{nil, map[string]*bintree{
+		"e2e": &bintree{nil, map[string]*bintree{
+			"manifests": &bintree{nil, map[string]*bintree{
+				"connectivity": &bintree{nil, map[string]*bintree{
+					"pod.yaml": &bintree{testE2eManifestsConnectivityPodYaml, map[string]*bintree{}},
+				}},
+				"preflight": &bintree{nil, map[string]*bintree{
+					"pod.yaml": &bintree{testE2eManifestsPreflightPodYaml, map[string]*bintree{}},
+				}},
+			}},
+			"storage": &bintree{nil, map[string]*bintree{
+				"manifests": &bintree{nil, map[string]*bintree{
+					"external-attacher": &bintree{nil, map[string]*bintree{
+						"rbac.yaml": &bintree{testE2eStorageManifestsExternalAttacherRbacYaml, map[string]*bintree{}},
+					}},
+					"external-provisioner": &bintree{nil, map[string]*bintree{
+						"rbac.yaml": &bintree{testE2eStorageManifestsExternalProvisionerRbacYaml, map[string]*bintree{}},
+					}},
+					"hostpath": &bintree{nil, map[string]*bintree{
+						"example": &bintree{nil, map[string]*bintree{
+							"usage": &bintree{nil, map[string]*bintree{
+								"csi-storageclass.yaml": &bintree{testE2eStorageManifestsHostpathExampleUsageCsiStorageclassYaml, map[string]*bintree{}},
+							}},
+						}},
+						"hostpath": &bintree{nil, map[string]*bintree{
+							"csi-hostpath-attacher.yaml":    &bintree{testE2eStorageManifestsHostpathHostpathCsiHostpathAttacherYaml, map[string]*bintree{}},
+							"csi-hostpath-provisioner.yaml": &bintree{testE2eStorageManifestsHostpathHostpathCsiHostpathProvisionerYaml, map[string]*bintree{}},
+							"csi-hostpathplugin.yaml":       &bintree{testE2eStorageManifestsHostpathHostpathCsiHostpathpluginYaml, map[string]*bintree{}},
+						}},
+						"usage": &bintree{nil, map[string]*bintree{
+							"csi-storageclass.yaml": &bintree{testE2eStorageManifestsHostpathUsageCsiStorageclassYaml, map[string]*bintree{}},
+						}},
+					}},
+				}},
+			}},
+		}},
+	}},
+}}
+
+// RestoreAsset restores an asset under the given directory
+func RestoreAsset(dir, name string) error {
+	data, err := Asset(name)
+	if err != nil {
+		return err
+	}
+	info, err := AssetInfo(name)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(_filePath(dir, filepath.Dir(name)), os.FileMode(0755))
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(_filePath(dir, name), data, info.Mode())
+	if err != nil {
+		return err
+	}
+	err = os.Chtimes(_filePath(dir, name), info.ModTime(), info.ModTime())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RestoreAssets restores an asset under the given directory recursively
+func RestoreAssets(dir, name string) error {
+	children, err := AssetDir(name)
+	// File
+	if err != nil {
+		return RestoreAsset(dir, name)
+	}
+	// Dir
+	for _, child := range children {
+		err = RestoreAssets(dir, filepath.Join(name, child))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func _filePath(dir, name string) string {
+	cannonicalName := strings.Replace(name, "\\", "/", -1)
+	return filepath.Join(append([]string{dir}, strings.Split(cannonicalName, "/")...)...)
+}
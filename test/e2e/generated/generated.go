@@ -0,0 +1,38 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package generated contains the files that tests read at runtime,
+// embedded with go-bindata. After changing one of those files,
+// bindata.go must be updated with "go generate ./test/e2e/generated"
+// or "make generated", using go-bindata v3.1.2 from
+// github.com/go-bindata/go-bindata.
+package generated
+
+import (
+	"k8s.io/kubernetes/test/e2e/framework/testfiles"
+)
+
+//go:generate go-bindata -pkg generated -nometadata -ignore \.md$ -prefix ../../../ -o bindata.go ../../../test/e2e/storage/manifests/... ../../../test/e2e/manifests/...
+//go:generate gofmt -w bindata.go
+
+// FileSource returns a file source for the embedded files, with the
+// same paths as relative to the repository root.
+func FileSource() testfiles.FileSource {
+	return testfiles.BindataFileSource{
+		Asset:      Asset,
+		AssetNames: AssetNames,
+	}
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/util/version"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/test/e2e/framework"
+
+	"github.com/kubernetes-csi/csi-e2e/test/e2e/storage"
+)
+
+// Registered during variable initialization because flags get parsed
+// in the init function of e2e_test.go.
+var preflight = flag.Bool("csi.preflight", false,
+	"Check that the cluster meets the prerequisites of CSI drivers before running tests and abort if it does not.")
+
+const preflightPodManifest = "test/e2e/manifests/preflight/pod.yaml"
+
+// The checks done by the pre-flight pod.
+var preflightChecks = []string{"plugins registry", "mount propagation"}
+
+// Kubelet feature gates that CSI drivers depend on. They are enabled
+// by default, so only explicitly disabled gates are a problem.
+var csiFeatureGates = []string{"CSIPersistentVolume", "KubeletPluginsWatcher", "MountPropagation"}
+
+// preflightProblem is a prerequisite that is not met on a node or,
+// if node is empty, in the cluster.
+type preflightProblem struct {
+	node, check, problem string
+}
+
+// checkCSIPrerequisites verifies that drivers can be deployed in the
+// cluster and fails with a table of all problems if not. It must be
+// called once before running tests.
+func checkCSIPrerequisites(c clientset.Interface) {
+	var problems []preflightProblem
+	problems = append(problems, checkServerVersion(c)...)
+	problems = append(problems, checkCSIAPI(c)...)
+	problems = append(problems, checkKubeletFeatureGates(c)...)
+	problems = append(problems, checkNodePrerequisites(c)...)
+	if len(problems) == 0 {
+		framework.Logf("CSI pre-flight checks passed")
+		return
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].node < problems[j].node
+	})
+	var buffer bytes.Buffer
+	w := tabwriter.NewWriter(&buffer, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tCHECK\tPROBLEM")
+	for _, p := range problems {
+		node := p.node
+		if node == "" {
+			node = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", node, p.check, p.problem)
+	}
+	w.Flush()
+	framework.Failf("CSI pre-flight checks failed:\n%s", buffer.String())
+}
+
+// checkServerVersion compares the server version against the
+// minimum version of each selected driver. Without the pre-flight
+// checks, the tests of such a driver merely get skipped.
+func checkServerVersion(c clientset.Interface) []preflightProblem {
+	const check = "server version"
+	info, err := c.Discovery().ServerVersion()
+	if err != nil {
+		return []preflightProblem{{check: check, problem: err.Error()}}
+	}
+	serverVersion, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return []preflightProblem{{check: check, problem: err.Error()}}
+	}
+
+	var problems []preflightProblem
+	minVersions := storage.MinKubeVersions()
+	var drivers []string
+	for driver := range minVersions {
+		drivers = append(drivers, driver)
+	}
+	sort.Strings(drivers)
+	for _, driver := range drivers {
+		minVersion, err := version.ParseGeneric(minVersions[driver])
+		if err != nil {
+			problems = append(problems, preflightProblem{check: check, problem: fmt.Sprintf("driver %s: %v", driver, err)})
+			continue
+		}
+		if !serverVersion.AtLeast(minVersion) {
+			problems = append(problems, preflightProblem{check: check, problem: fmt.Sprintf("driver %s needs at least %s, server is %s", driver, minVersions[driver], info.GitVersion)})
+		}
+	}
+	return problems
+}
+
+// checkCSIAPI verifies that the API server supports CSI.
+func checkCSIAPI(c clientset.Interface) []preflightProblem {
+	resources, err := c.Discovery().ServerResourcesForGroupVersion("storage.k8s.io/v1")
+	if err == nil {
+		for _, resource := range resources.APIResources {
+			if resource.Name == "volumeattachments" {
+				return nil
+			}
+		}
+	}
+	problem := "storage.k8s.io/v1 VolumeAttachment API not available"
+	if err != nil {
+		problem += ": " + err.Error()
+	}
+	return []preflightProblem{{check: "CSI API", problem: problem}}
+}
+
+// checkKubeletFeatureGates looks for disabled CSI feature gates in
+// the kubelet configuration. Nodes whose configuration cannot be
+// retrieved are skipped.
+func checkKubeletFeatureGates(c clientset.Interface) []preflightProblem {
+	var problems []preflightProblem
+	for _, node := range framework.GetReadySchedulableNodesOrDie(c).Items {
+		data, err := c.CoreV1().RESTClient().Get().Resource("nodes").Name(node.Name).SubResource("proxy").Suffix("configz").DoRaw()
+		if err != nil {
+			framework.Logf("Cannot check kubelet feature gates on node %s: %v", node.Name, err)
+			continue
+		}
+		var configz struct {
+			KubeletConfig struct {
+				FeatureGates map[string]bool `json:"featureGates"`
+			} `json:"kubeletconfig"`
+		}
+		if err := json.Unmarshal(data, &configz); err != nil {
+			framework.Logf("Cannot check kubelet feature gates on node %s: %v", node.Name, err)
+			continue
+		}
+		for _, gate := range csiFeatureGates {
+			if enabled, ok := configz.KubeletConfig.FeatureGates[gate]; ok && !enabled {
+				problems = append(problems, preflightProblem{node: node.Name, check: "feature gates", problem: gate + " disabled in kubelet"})
+			}
+		}
+	}
+	return problems
+}
+
+// checkNodePrerequisites runs a privileged probe pod on each node.
+func checkNodePrerequisites(c clientset.Interface) []preflightProblem {
+	nodes, results, err := runProbePods(c, preflightPodManifest, preflightChecks, nil)
+	if err != nil {
+		return []preflightProblem{{check: "probe pod", problem: err.Error()}}
+	}
+	var problems []preflightProblem
+	for _, node := range nodes {
+		result := results[node]
+		// When the pod did not run, for example because
+		// privileged pods are not allowed, all checks have the
+		// same reason. It gets reported only once.
+		reason := result[preflightChecks[0]]
+		same := true
+		for _, check := range preflightChecks {
+			same = same && result[check] == reason
+		}
+		if same && strings.HasPrefix(reason, "unknown: ") {
+			problems = append(problems, preflightProblem{node: node, check: "probe pod", problem: strings.TrimPrefix(reason, "unknown: ")})
+			continue
+		}
+		for _, check := range preflightChecks {
+			if result[check] != "ok" {
+				problems = append(problems, preflightProblem{node: node, check: check, problem: result[check]})
+			}
+		}
+	}
+	return problems
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/api/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/test/e2e/framework"
+	"k8s.io/kubernetes/test/e2e/manifest"
+	imageutils "k8s.io/kubernetes/test/utils/image"
+
+	"github.com/kubernetes-csi/csi-e2e/test/e2e/storage"
+)
+
+const probeTimeout = 5 * time.Minute
+
+// probeImages maps the image names used in the probe pod manifests
+// to the images configured for the test run.
+var probeImages = map[string]imageutils.Config{
+	"busybox":  imageutils.BusyBox,
+	"dnsutils": imageutils.Dnsutils,
+}
+
+// probeResult maps the name of a check to "ok" or a description of
+// the problem. Probe pods print one line per check with
+// "<check>: ok" or "<check>: failed: <reason>".
+type probeResult map[string]string
+
+// runProbePods runs one instance of the pod from the manifest on
+// each node that test drivers may get pinned to, after modifying it
+// with the optional patch function. It returns the names of those
+// nodes and the result of each check per node. When a pod cannot be
+// created or does not complete, all of its checks are reported as
+// unknown.
+//
+// The pods run in a new namespace with the label that
+// -csi.clean-start looks for, in case that they do not get removed.
+func runProbePods(c clientset.Interface, podManifest string, checks []string, patch func(pod *v1.Pod)) ([]string, map[string]probeResult, error) {
+	pod, err := manifest.PodFromManifest(podManifest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse %s: %v", podManifest, err)
+	}
+	namespace, err := storage.CreateTestingNS("csi-probe", c, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create namespace for probe pods: %v", err)
+	}
+	ns := namespace.Name
+	defer func() {
+		if err := c.CoreV1().Namespaces().Delete(ns, nil); err != nil {
+			framework.Logf("Failed to delete namespace %s: %v", ns, err)
+		}
+	}()
+	pod.Namespace = ns
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		image, ok := probeImages[container.Image]
+		if !ok {
+			return nil, nil, fmt.Errorf("%s: unknown image %q", podManifest, container.Image)
+		}
+		container.Image = imageutils.GetE2EImage(image)
+	}
+	if patch != nil {
+		patch(pod)
+	}
+
+	// Start all pods first, then collect their results.
+	var nodeNames []string
+	pods := map[string]*v1.Pod{}
+	results := map[string]probeResult{}
+	for _, node := range framework.GetReadySchedulableNodesOrDie(c).Items {
+		nodeNames = append(nodeNames, node.Name)
+		p := pod.DeepCopy()
+		p.Spec.NodeName = node.Name
+		p, err := c.CoreV1().Pods(ns).Create(p)
+		if err != nil {
+			results[node.Name] = unknownProbeResult(checks, fmt.Sprintf("create pod: %v", err))
+			continue
+		}
+		pods[node.Name] = p
+	}
+	defer func() {
+		for _, p := range pods {
+			if err := c.CoreV1().Pods(ns).Delete(p.Name, nil); err != nil {
+				framework.Logf("Failed to delete pod %v: %v", p.Name, err)
+			}
+		}
+	}()
+	for node, p := range pods {
+		results[node] = getProbeResult(c, p, checks)
+	}
+	return nodeNames, results, nil
+}
+
+// getProbeResult waits for the pod to terminate and parses the
+// output of all containers.
+func getProbeResult(c clientset.Interface, pod *v1.Pod, checks []string) probeResult {
+	err := framework.WaitForPodCondition(c, pod.Namespace, pod.Name, "terminated", probeTimeout, func(pod *v1.Pod) (bool, error) {
+		return pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed, nil
+	})
+	if err != nil {
+		return unknownProbeResult(checks, fmt.Sprintf("pod %s did not run: %v", pod.Name, err))
+	}
+	result := probeResult{}
+	for _, container := range pod.Spec.Containers {
+		logs, err := framework.GetPodLogs(c, pod.Namespace, pod.Name, container.Name)
+		if err != nil {
+			return unknownProbeResult(checks, fmt.Sprintf("get output of pod %s: %v", pod.Name, err))
+		}
+		for _, line := range strings.Split(logs, "\n") {
+			parts := strings.SplitN(line, ": ", 2)
+			if len(parts) == 2 {
+				result[parts[0]] = parts[1]
+			}
+		}
+	}
+	for _, check := range checks {
+		if result[check] == "" {
+			result[check] = "unknown: no result in pod output"
+		}
+	}
+	return result
+}
+
+// unknownProbeResult returns a result where all checks failed for
+// the same reason.
+func unknownProbeResult(checks []string, reason string) probeResult {
+	result := probeResult{}
+	for _, check := range checks {
+		result[check] = "unknown: " + reason
+	}
+	return result
+}
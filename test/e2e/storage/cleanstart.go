@@ -0,0 +1,216 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/test/e2e/framework"
+)
+
+// createdLabel is set for all namespaces and cluster-scoped objects
+// that csi-e2e creates, so that they can be found again by a later
+// run.
+const createdLabel = "csi-e2e"
+
+// labelItem adds createdLabel to an object from a manifest.
+func labelItem(item interface{}) {
+	object, ok := item.(metav1.Object)
+	if !ok {
+		return
+	}
+	labels := object.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[createdLabel] = "true"
+	object.SetLabels(labels)
+}
+
+// CreateTestingNS is a replacement for framework.CreateTestingNS which
+// adds createdLabel to the namespace. It must be stored in
+// framework.TestContext.CreateTestingNS.
+func CreateTestingNS(baseName string, c clientset.Interface, labels map[string]string) (*v1.Namespace, error) {
+	withLabel := map[string]string{createdLabel: "true"}
+	for key, value := range labels {
+		withLabel[key] = value
+	}
+	return framework.CreateTestingNS(baseName, c, withLabel)
+}
+
+// CleanStart removes everything that earlier csi-e2e runs left
+// behind: namespaces and cluster-scoped objects with createdLabel
+// and cluster-scoped objects of renamed drivers whose namespace no
+// longer exists, like PVs whose finalizer would never get removed.
+// It must be called once before running tests and must not be used
+// while some other csi-e2e run is active in the same cluster.
+func CleanStart() error {
+	cs, csiClient, err := loadClients()
+	if err != nil {
+		return err
+	}
+	selector := metav1.ListOptions{LabelSelector: createdLabel}
+
+	// Renamed drivers contain the name of the namespace that they
+	// were deployed for.
+	prefixes := renamedDriverPrefixes()
+	staleNamespaces := sets.NewString()
+	staleDrivers := sets.NewString()
+	namespaces, err := cs.CoreV1().Namespaces().List(selector)
+	if err != nil {
+		return fmt.Errorf("listing namespaces: %v", err)
+	}
+	for _, ns := range namespaces.Items {
+		staleNamespaces.Insert(ns.Name)
+		for _, prefix := range prefixes {
+			staleDrivers.Insert(prefix + ns.Name)
+		}
+		if ns.DeletionTimestamp != nil {
+			continue
+		}
+		framework.Logf("deleting namespace %s", ns.Name)
+		if err := cs.CoreV1().Namespaces().Delete(ns.Name, nil); err != nil && !apierrs.IsNotFound(err) {
+			return fmt.Errorf("deleting namespace %s: %v", ns.Name, err)
+		}
+	}
+	if staleNamespaces.Len() > 0 {
+		if err := framework.WaitForNamespacesDeleted(cs, staleNamespaces.List(), framework.NamespaceCleanupTimeout); err != nil {
+			return err
+		}
+	}
+
+	var labeled []leakedObject
+	clusterRoles, err := cs.RbacV1().ClusterRoles().List(selector)
+	if err != nil {
+		return fmt.Errorf("listing ClusterRoles: %v", err)
+	}
+	for _, item := range clusterRoles.Items {
+		name := item.Name
+		labeled = append(labeled, leakedObject{"ClusterRole", name, func() error {
+			return cs.RbacV1().ClusterRoles().Delete(name, nil)
+		}})
+	}
+	clusterRoleBindings, err := cs.RbacV1().ClusterRoleBindings().List(selector)
+	if err != nil {
+		return fmt.Errorf("listing ClusterRoleBindings: %v", err)
+	}
+	for _, item := range clusterRoleBindings.Items {
+		name := item.Name
+		labeled = append(labeled, leakedObject{"ClusterRoleBinding", name, func() error {
+			return cs.RbacV1().ClusterRoleBindings().Delete(name, nil)
+		}})
+	}
+	storageClasses, err := cs.StorageV1().StorageClasses().List(selector)
+	if err != nil {
+		return fmt.Errorf("listing StorageClasses: %v", err)
+	}
+	for _, item := range storageClasses.Items {
+		name := item.Name
+		labeled = append(labeled, leakedObject{"StorageClass", name, func() error {
+			return cs.StorageV1().StorageClasses().Delete(name, nil)
+		}})
+	}
+	removeObjects(labeled)
+
+	// PVs and VolumeAttachments of a driver which was removed
+	// before them can stay around forever. Their driver name tells
+	// which namespace the driver was in. Only names that csi-e2e
+	// could have generated are considered, because drivers of
+	// other users might follow a similar naming scheme.
+	var drivers []string
+	pvs, err := cs.CoreV1().PersistentVolumes().List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("listing PersistentVolumes: %v", err)
+	}
+	for _, pv := range pvs.Items {
+		if pv.Spec.CSI != nil {
+			drivers = append(drivers, pv.Spec.CSI.Driver)
+		}
+	}
+	attachments, err := cs.StorageV1().VolumeAttachments().List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("listing VolumeAttachments: %v", err)
+	}
+	for _, attachment := range attachments.Items {
+		drivers = append(drivers, attachment.Spec.Attacher)
+	}
+	for _, driver := range drivers {
+		if staleDrivers.Has(driver) {
+			continue
+		}
+		ns := renamedDriverNamespace(driver, prefixes)
+		if ns == "" {
+			continue
+		}
+		_, err := cs.CoreV1().Namespaces().Get(ns, metav1.GetOptions{})
+		switch {
+		case apierrs.IsNotFound(err):
+			staleDrivers.Insert(driver)
+		case err != nil:
+			return fmt.Errorf("checking namespace of driver %s: %v", driver, err)
+		}
+	}
+
+	// This also finds objects without createdLabel, like PVs,
+	// VolumeAttachments and entries in CSINodeInfo. Names are
+	// compared exactly, so objects that merely contain a stale
+	// name are not affected.
+	removeObjects(findLeakedObjects(cs, csiClient, func(name string) bool {
+		return staleDrivers.Has(name) || staleNamespaces.Has(name)
+	}))
+	return nil
+}
+
+// generatedNamespaceRE matches the names of the namespaces that
+// csi-e2e creates: framework.CreateTestingNS appends a random number
+// to the base name, which is "csi" for tests and "csi-driver" for
+// shared drivers.
+var generatedNamespaceRE = regexp.MustCompile(`^csi(-driver)?-[0-9]+$`)
+
+// renamedDriverNamespace returns the namespace that a renamed driver
+// was deployed for, or an empty string if the driver name cannot
+// have been generated by csi-e2e.
+func renamedDriverNamespace(driver string, prefixes []string) string {
+	for _, prefix := range prefixes {
+		if !strings.HasPrefix(driver, prefix) {
+			continue
+		}
+		if ns := strings.TrimPrefix(driver, prefix); generatedNamespaceRE.MatchString(ns) {
+			return ns
+		}
+	}
+	return ""
+}
+
+// renamedDriverPrefixes returns the driver names, without the unique
+// suffix, of all drivers that get renamed for each test.
+func renamedDriverPrefixes() []string {
+	var prefixes []string
+	for _, initDriver := range csiTestDrivers {
+		if m, ok := initDriver(nil).(*manifestDriver); ok && strings.HasSuffix(m.patchOptions.NewDriverName, "-") {
+			prefixes = append(prefixes, m.patchOptions.NewDriverName)
+		}
+	}
+	return prefixes
+}
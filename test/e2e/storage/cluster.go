@@ -0,0 +1,240 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/version"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/test/e2e/framework"
+	"k8s.io/kubernetes/test/e2e/storage/testpatterns"
+)
+
+// Cluster features that drivers and test suites may depend on. Most
+// names are those of the Kubernetes feature gates which enable the
+// feature. Topology stands for CSI topology support, which is not
+// controlled by a gate of its own.
+const (
+	FeatureCSIDriver      = "CSIDriver"
+	FeatureCSINodeInfo    = "CSINodeInfo"
+	FeatureBlockVolume    = "BlockVolume"
+	FeatureCSIBlockVolume = "CSIBlockVolume"
+	FeatureTopology       = "Topology"
+)
+
+// featureDetection determines whether a feature is available in the
+// cluster. API resources are checked through discovery. Feature gates
+// which have no visible effect in the API are assumed to be enabled
+// when they are enabled by default in the server version.
+type featureDetection struct {
+	// Resources in the form <group>/<version>/<resource>, one of
+	// them must be served.
+	resources []string
+	// Server version in which the feature is enabled by default.
+	defaultSince string
+	// Other features that must be available.
+	requires []string
+}
+
+var features = map[string]featureDetection{
+	FeatureCSIDriver: {
+		resources: []string{"csi.storage.k8s.io/v1alpha1/csidrivers", "storage.k8s.io/v1beta1/csidrivers"},
+	},
+	FeatureCSINodeInfo: {
+		resources: []string{"csi.storage.k8s.io/v1alpha1/csinodeinfos", "storage.k8s.io/v1beta1/csinodes"},
+	},
+	FeatureBlockVolume: {
+		defaultSince: "v1.13.0",
+	},
+	// Raw block support for CSI drivers is controlled
+	// separately from the in-tree volume plugins.
+	FeatureCSIBlockVolume: {
+		defaultSince: "v1.14.0",
+		requires:     []string{FeatureBlockVolume},
+	},
+	FeatureTopology: {
+		defaultSince: "v1.14.0",
+		requires:     []string{FeatureCSINodeInfo},
+	},
+}
+
+// featureOverrides is a flag value of the form <feature>=<bool>,...
+type featureOverrides map[string]bool
+
+func (f featureOverrides) String() string {
+	var parts []string
+	for feature, enabled := range f {
+		parts = append(parts, fmt.Sprintf("%s=%v", feature, enabled))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (f featureOverrides) Set(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("%q: must be <feature>=<true|false>", part)
+		}
+		if _, ok := features[kv[0]]; !ok {
+			return fmt.Errorf("unknown feature %q, must be one of: %s", kv[0], strings.Join(knownFeatures(), ", "))
+		}
+		enabled, err := strconv.ParseBool(kv[1])
+		if err != nil {
+			return fmt.Errorf("%q: %v", part, err)
+		}
+		f[kv[0]] = enabled
+	}
+	return nil
+}
+
+var overriddenFeatures = featureOverrides{}
+
+func init() {
+	flag.Var(overriddenFeatures, "csi.features",
+		fmt.Sprintf("Comma-separated list of <feature>=<true|false> which replaces the detection of cluster features (%s).", strings.Join(knownFeatures(), ", ")))
+}
+
+func knownFeatures() []string {
+	var names []string
+	for name := range features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cluster describes the cluster that the tests run against. It is
+// only known after DetectCluster was called.
+var cluster struct {
+	version  *version.Version
+	features sets.String
+}
+
+// DetectCluster determines server version and available features. It
+// must be called on each Ginkgo node before running tests.
+func DetectCluster(c clientset.Interface) error {
+	info, err := c.Discovery().ServerVersion()
+	if err != nil {
+		return fmt.Errorf("retrieving server version: %v", err)
+	}
+	serverVersion, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return fmt.Errorf("server version: %v", err)
+	}
+
+	available := sets.NewString()
+	// Sorted so that required features are known when a feature
+	// needs them. Required features do not depend on others.
+	names := knownFeatures()
+	sort.SliceStable(names, func(i, j int) bool {
+		return len(features[names[i]].requires) < len(features[names[j]].requires)
+	})
+	for _, name := range names {
+		enabled, ok := overriddenFeatures[name]
+		if !ok {
+			enabled, err = detectFeature(c, serverVersion, features[name], available)
+			if err != nil {
+				return fmt.Errorf("detecting feature %s: %v", name, err)
+			}
+		}
+		if enabled {
+			available.Insert(name)
+		}
+	}
+
+	cluster.version = serverVersion
+	cluster.features = available
+	framework.Logf("cluster version %s, available features: %s", serverVersion, strings.Join(available.List(), ", "))
+	return nil
+}
+
+func detectFeature(c clientset.Interface, serverVersion *version.Version, detection featureDetection, available sets.String) (bool, error) {
+	if !available.HasAll(detection.requires...) {
+		return false, nil
+	}
+	if detection.defaultSince != "" {
+		return serverVersion.AtLeast(version.MustParseGeneric(detection.defaultSince)), nil
+	}
+	for _, resource := range detection.resources {
+		i := strings.LastIndex(resource, "/")
+		list, err := c.Discovery().ServerResourcesForGroupVersion(resource[:i])
+		if apierrs.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		for _, r := range list.APIResources {
+			if r.Name == resource[i+1:] {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// ServerVersion returns the version of the cluster, nil when not
+// known yet.
+func ServerVersion() *version.Version {
+	return cluster.version
+}
+
+// HasFeature returns true if the feature is available in the cluster.
+func HasFeature(feature string) bool {
+	return cluster.features.Has(feature)
+}
+
+// skipUnlessClusterSupports skips the current test when the cluster
+// is older than the minimum version or lacks one of the features.
+// Nothing is skipped while the cluster is unknown, for example when
+// listing tests.
+func skipUnlessClusterSupports(what, minKubeVersion string, requiredFeatures ...string) {
+	if cluster.version == nil {
+		return
+	}
+	if minKubeVersion != "" {
+		minVersion, err := version.ParseGeneric(minKubeVersion)
+		framework.ExpectNoError(err, "minimum Kubernetes version of %s", what)
+		if !cluster.version.AtLeast(minVersion) {
+			framework.Skipf("%s needs at least Kubernetes %s, cluster has %s -- skipping", what, minKubeVersion, cluster.version)
+		}
+	}
+	if missing := sets.NewString(requiredFeatures...).Difference(cluster.features); missing.Len() > 0 {
+		framework.Skipf("%s needs cluster features %s which are not available -- skipping", what, strings.Join(missing.List(), ", "))
+	}
+}
+
+// patternFeatures returns the cluster features that tests with the
+// pattern depend on.
+func patternFeatures(pattern testpatterns.TestPattern) []string {
+	if pattern.VolMode == v1.PersistentVolumeBlock {
+		return []string{FeatureCSIBlockVolume}
+	}
+	return nil
+}
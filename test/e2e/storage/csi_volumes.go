@@ -20,10 +20,13 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"path"
+	"regexp"
 	"strings"
 
 	"k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/kubernetes/test/e2e/framework"
@@ -32,6 +35,8 @@ import (
 	"k8s.io/kubernetes/test/e2e/storage/testsuites"
 	"k8s.io/kubernetes/test/e2e/storage/utils"
 
+	"github.com/kubernetes-csi/csi-e2e/pkg/csiproxy"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -47,10 +52,138 @@ func csiTunePattern(patterns []testpatterns.TestPattern) []testpatterns.TestPatt
 		tunedPatterns = append(tunedPatterns, pattern)
 	}
 
-	return tunedPatterns
+	return selectPatterns(tunedPatterns)
+}
+
+// List of test drivers to be tested against. Each function returns a
+// new driver instance for use with the given framework.
+var csiTestDrivers = []func(f *framework.Framework) testsuites.TestDriver{
+	// hostpath driver
+	func(f *framework.Framework) testsuites.TestDriver {
+		return &manifestDriver{
+			driverInfo: testsuites.DriverInfo{
+				Name:        "csi-hostpath",
+				MaxFileSize: testpatterns.FileSizeMedium,
+				SupportedFsType: sets.NewString(
+					"", // Default fsType
+				),
+				Capabilities: map[testsuites.Capability]bool{
+					testsuites.CapPersistence: true,
+					testsuites.CapFsGroup:     true,
+					testsuites.CapExec:        true,
+				},
+
+				Config: testsuites.TestConfig{
+					Framework: f,
+					Prefix:    "csi",
+				},
+			},
+			manifests: []string{
+				"test/e2e/storage/manifests/external-attacher/rbac.yaml",
+				"test/e2e/storage/manifests/external-provisioner/rbac.yaml",
+				"test/e2e/storage/manifests/hostpath/hostpath/csi-hostpath-attacher.yaml",
+				"test/e2e/storage/manifests/hostpath/hostpath/csi-hostpath-provisioner.yaml",
+				"test/e2e/storage/manifests/hostpath/hostpath/csi-hostpathplugin.yaml",
+			},
+			scManifest: "test/e2e/storage/manifests/hostpath/example/usage/csi-storageclass.yaml",
+			// Enable renaming of the driver.
+			patchOptions: utils.PatchCSIOptions{
+				OldDriverName:            "csi-hostpath",
+				NewDriverName:            "csi-hostpath-", // f.UniqueName must be added later
+				DriverContainerName:      "hostpath",
+				ProvisionerContainerName: "csi-provisioner",
+			},
+			claimSize: "1Mi",
+			// The hostpath driver logs all gRPC requests as JSON with sorted keys.
+			readOnlyPublishLog: `GRPC request: \{.*"readonly":true.*"volume_id":"%s"`,
+			// The hostpath driver stores each volume in a directory under /tmp.
+			volumeExistsCommand: "if test -e /tmp/%s; then echo exists; fi",
+			csiEndpoint:         "/csi/csi.sock",
+			// CSI 1.0 sidecars need Kubernetes 1.13.
+			minKubeVersion: "v1.13.0",
+
+			// The actual node on which the driver and the test pods run must
+			// be set at runtime because it cannot be determined in advance.
+			beforeEach: func(m *manifestDriver) {
+				nodes := framework.GetReadySchedulableNodesOrDie(m.driverInfo.Config.Framework.ClientSet)
+				node := nodes.Items[rand.Intn(len(nodes.Items))]
+				m.driverInfo.Config.ClientNodeName = node.Name
+				m.patchOptions.NodeName = node.Name
+
+			},
+		}
+	},
+}
+
+// List of test suites to be executed for each driver. The names must
+// be the same as in the testsuites package, which does not reveal
+// them.
+var csiTestSuites = []namedTestSuite{
+	{"volumes", testsuites.InitVolumesTestSuite},
+	{"volumeIO", testsuites.InitVolumeIOTestSuite},
+	{"volumeMode", testsuites.InitVolumeModeTestSuite},
+	{"subPath", testsuites.InitSubPathTestSuite},
+	{"provisioning", testsuites.InitProvisioningTestSuite},
+}
+
+// namedTestSuite is a test suite from the testsuites package.
+type namedTestSuite struct {
+	name string
+	init func() testsuites.TestSuite
+}
+
+// List of additional test suites from this package.
+var csiExtraTestSuites = []func() csiTestSuite{
+	initMountOptionsTestSuite,
+	initReadOnlyTestSuite,
+	initReclaimPolicyTestSuite,
+}
+
+// definedDrivers contains the driver instances for which tests were
+// defined, indexed by their name in the test matrix. Listing tests
+// and rendering manifests use it without running any test.
+var definedDrivers = map[string]testsuites.TestDriver{}
+
+// MinKubeVersions returns the oldest supported Kubernetes release for
+// each driver which defines one, indexed by driver name. It must be
+// called after DefineTests.
+func MinKubeVersions() map[string]string {
+	versions := map[string]string{}
+	for _, driver := range definedDrivers {
+		if m, ok := driver.(*manifestDriver); ok && m.minKubeVersion != "" {
+			versions[m.driverInfo.Name] = m.minKubeVersion
+		}
+	}
+	return versions
 }
 
-var _ = Describe("CSI Volumes", func() {
+// DefineTests defines all tests in this package. It must be called
+// after parsing the command line because the set of tests depends on
+// some flags. Invalid values for those flags are returned as error.
+func DefineTests() error {
+	if err := validateSelection(); err != nil {
+		return err
+	}
+	if err := validateHold(); err != nil {
+		return err
+	}
+	Describe("CSI Volumes", csiVolumes)
+	return nil
+}
+
+func csiVolumes() {
+	// These get registered before the framework's own BeforeEach
+	// and AfterEach and thus run before those, which allows
+	// timing namespace creation and deletion.
+	var stopPhase func()
+	BeforeEach(func() {
+		startTestPhases()
+		stopPhase = startPhase(phaseCreateNamespace)
+	})
+	AfterEach(func() {
+		stopPhase = startPhase(phaseDeleteNamespace)
+	})
+
 	f := framework.NewDefaultFramework("csi")
 
 	var (
@@ -61,120 +194,124 @@ var _ = Describe("CSI Volumes", func() {
 	)
 
 	BeforeEach(func() {
+		stopPhase()
 		cs = f.ClientSet
 		ns = f.Namespace
+		recordUniqueName(f.UniqueName)
 		// These local variables are needed to appease "go vet".
 		// It warns about not calling cancel otherwise.
 		c, cncl := context.WithCancel(context.Background())
 		ctx = c
 		cancel = cncl
 
-		// We copy all output from pods directly to the
-		// GingkoWriter.
-		//
-		// When using a more elaborate CI system which uses
-		// the --report-dir feature to capture log files, then
-		// see k8s.io/kubernetes/test/e2e/storage/csi_volumes.go
-		// for an example how the output can also get
-		// redirected to log files.
+		// Without a report directory, we copy all output from
+		// pods directly to the GingkoWriter. Otherwise each
+		// container gets its own log file in a directory named
+		// after the test and only status messages go to the
+		// GinkgoWriter.
 		to := podlogs.LogOutput{
 			StatusWriter: GinkgoWriter,
-			LogWriter:    GinkgoWriter,
+		}
+		if dir := testReportDir(); dir == "" {
+			to.LogWriter = GinkgoWriter
+		} else {
+			// We end the prefix with a slash to ensure that all logs
+			// end up in the directory.
+			to.LogPathPrefix = dir + "/"
+			framework.Logf("writing pod logs to %s", dir)
 		}
 		podlogs.CopyAllLogs(ctx, cs, ns.Name, to)
 		podlogs.WatchPods(ctx, cs, ns.Name, GinkgoWriter)
+		if err := watchTestPhases(ctx, cs, ns.Name); err != nil {
+			framework.Logf("ERROR: test phases of volumes and pods will be missing: %v", err)
+		}
 	})
 
 	AfterEach(func() {
 		cancel()
+		stopPhase()
+		finishTestPhases()
 	})
 
-	// List of test drivers to be tested against.
-	var csiTestDrivers = []func() testsuites.TestDriver{
-		// hostpath driver
-		func() testsuites.TestDriver {
-			return &manifestDriver{
-				driverInfo: testsuites.DriverInfo{
-					Name:        "csi-hostpath",
-					MaxFileSize: testpatterns.FileSizeMedium,
-					SupportedFsType: sets.NewString(
-						"", // Default fsType
-					),
-					Capabilities: map[testsuites.Capability]bool{
-						testsuites.CapPersistence: true,
-						testsuites.CapFsGroup:     true,
-						testsuites.CapExec:        true,
-					},
-
-					Config: testsuites.TestConfig{
-						Framework: f,
-						Prefix:    "csi",
-					},
-				},
-				manifests: []string{
-					"test/e2e/storage/manifests/external-attacher/rbac.yaml",
-					"test/e2e/storage/manifests/external-provisioner/rbac.yaml",
-					"test/e2e/storage/manifests/hostpath/hostpath/csi-hostpath-attacher.yaml",
-					"test/e2e/storage/manifests/hostpath/hostpath/csi-hostpath-provisioner.yaml",
-					"test/e2e/storage/manifests/hostpath/hostpath/csi-hostpathplugin.yaml",
-				},
-				scManifest: "test/e2e/storage/manifests/hostpath/example/usage/csi-storageclass.yaml",
-				// Enable renaming of the driver.
-				patchOptions: utils.PatchCSIOptions{
-					OldDriverName:            "csi-hostpath",
-					NewDriverName:            "csi-hostpath-", // f.UniqueName must be added later
-					DriverContainerName:      "hostpath",
-					ProvisionerContainerName: "csi-provisioner",
-				},
-				claimSize: "1Mi",
-
-				// The actual node on which the driver and the test pods run must
-				// be set at runtime because it cannot be determined in advance.
-				beforeEach: func(m *manifestDriver) {
-					nodes := framework.GetReadySchedulableNodesOrDie(cs)
-					node := nodes.Items[rand.Intn(len(nodes.Items))]
-					m.driverInfo.Config.ClientNodeName = node.Name
-					m.patchOptions.NodeName = node.Name
-
-				},
-			}
-		},
-	}
-
-	// List of test suites to be executed for each driver.
-	var csiTestSuites = []func() testsuites.TestSuite{
-		testsuites.InitVolumesTestSuite,
-		testsuites.InitVolumeIOTestSuite,
-		testsuites.InitVolumeModeTestSuite,
-		testsuites.InitSubPathTestSuite,
-		testsuites.InitProvisioningTestSuite,
-	}
-
+	suites := selectedTestSuites()
+	extraSuites := selectedCSITestSuites()
 	for _, initDriver := range csiTestDrivers {
-		curDriver := initDriver()
-		Context(testsuites.GetDriverNameWithFeatureTags(curDriver), func() {
-			driver := curDriver
-
-			BeforeEach(func() {
-				// setupDriver
-				driver.CreateDriver()
-			})
-
-			AfterEach(func() {
-				// Cleanup driver
-				driver.CleanupDriver()
+		if !selectedDrivers.selects(initDriver(f).GetDriverInfo().Name) {
+			continue
+		}
+		for _, versions := range sidecarCombinations(initDriver(f)) {
+			curDriver := initDriver(f)
+			name := testsuites.GetDriverNameWithFeatureTags(curDriver)
+			if versions != nil {
+				curDriver.(*manifestDriver).sidecarVersions = versions
+				name += " " + versions.String()
+			}
+			if driver, ok := matrixDriver(name); ok {
+				definedDrivers[driver] = curDriver
+			}
+			Context(name, func() {
+				driver := curDriver
+
+				BeforeEach(func() {
+					// Skip before deploying a driver which
+					// would not work anyway.
+					if m, ok := driver.(*manifestDriver); ok {
+						m.skipUnsupportedCluster()
+					}
+					// A failed assertion dumps the state
+					// while the objects of the test still
+					// exist.
+					setFailureDump(func() {
+						dumpTestState(f, driver)
+					})
+					// setupDriver
+					driver.CreateDriver()
+				})
+
+				AfterEach(func() {
+					// Nil if the state was already dumped
+					// when the test failed.
+					dump := takeFailureDump()
+					// Test suites clean up their own objects
+					// in their AfterEach, which runs before
+					// this one. Therefore we might not see
+					// all objects anymore, but events and
+					// the driver state are still there.
+					// All volumes should have been deleted,
+					// so now the CSI calls are complete.
+					failed := CurrentGinkgoTestDescription().Failed
+					var callsErr error
+					if !failed {
+						callsErr = verifyCSICalls(driver)
+					}
+					metricsErr := finishDriverMetrics(driver)
+					if dump != nil && (failed || callsErr != nil || metricsErr != nil) {
+						dump()
+					}
+
+					if failed && holdOnFailure {
+						holdDriver(f, driver)
+					}
+
+					// Cleanup driver
+					driver.CleanupDriver()
+
+					framework.ExpectNoError(callsErr, "invalid CSI calls")
+					framework.ExpectNoError(metricsErr, "driver metrics")
+				})
+
+				testsuites.RunTestSuite(f, driver, suites, csiTunePattern)
+				runCSITestSuites(driver, extraSuites, csiTunePattern)
 			})
-
-			testsuites.RunTestSuite(f, driver, csiTestSuites, csiTunePattern)
-		})
+		}
 	}
-})
+}
 
 // The manifestDriver implements the test driver interface based on
 // a list of yaml files that deploy the driver and a storage class
 // for that driver. It supports some additional configuration options
-// that control testing (claim size) and driver renaming. With
-// driver renaming, tests can run in parallel because each test
+// that control testing (claim size), driver renaming and secrets.
+// With driver renaming, tests can run in parallel because each test
 // deployes and removes its own driver instance.
 type manifestDriver struct {
 	driverInfo   testsuites.DriverInfo
@@ -182,12 +319,70 @@ type manifestDriver struct {
 	manifests    []string
 	scManifest   string
 	claimSize    string
-	beforeEach   func(m *manifestDriver)
-	cleanup      func()
+	// Secrets which get created in the test namespace and
+	// referenced by the storage class.
+	secrets []secretTemplate
+	// Regular expression for a line in the log output of the
+	// driver container which shows a NodePublishVolume call
+	// with readonly=true. %s is replaced with the quoted volume
+	// handle. Empty if not known.
+	readOnlyPublishLog string
+	// Shell command which prints "exists" when the volume exists
+	// in the storage backend. It runs inside the driver
+	// container. %s is replaced with the volume handle. Empty if
+	// not known.
+	volumeExistsCommand string
+	// The oldest Kubernetes release that the driver works with,
+	// like "v1.13.0". Empty if unknown.
+	minKubeVersion string
+	// Cluster features like FeatureCSIDriver that the driver
+	// depends on.
+	requiredFeatures []string
+	// Path of the CSI socket inside the driver container. The call
+	// recording proxy only gets injected when this is set.
+	csiEndpoint string
+	// Image versions that the driver gets tested with, see
+	// sidecarMatrix. Can be extended via the command line.
+	sidecarMatrix sidecarMatrix
+	// Containers which provide Prometheus metrics. Only scraped
+	// when enabled on the command line.
+	metricsEndpoints []metricsEndpoint
+	// Limits for the increase of metrics during a test, in
+	// addition to those from the command line.
+	metricsThresholds []metricsThreshold
+
+	beforeEach func(m *manifestDriver)
+	cleanup    func()
+	// The instance used by all tests on this Ginkgo node, nil
+	// unless enabled with -csi.share-drivers.
+	shared *sharedDeployment
+	// Number of CSI calls made by the shared instance before the
+	// current test.
+	callsBefore int
+	// Set by holdDriver when the current instance must be kept.
+	held bool
+
+	// The image versions used for this instance of the driver.
+	sidecarVersions sidecarVersions
+	// The images that patchItem found since the driver was last
+	// deployed or rendered.
+	patchedSidecars map[string]bool
+	// Secrets created for the current test.
+	createdSecrets []*v1.Secret
+	cleanupSecrets func()
+	// Collects metrics for the current test, nil if not enabled.
+	metrics *metricsCollector
+	// Samples resource usage while the driver instance of the
+	// current test runs, nil if not enabled or the instance is
+	// shared.
+	resources *resourceSampler
 }
 
 var _ testsuites.TestDriver = &manifestDriver{}
 var _ testsuites.DynamicPVTestDriver = &manifestDriver{}
+var _ readOnlyPublishChecker = &manifestDriver{}
+var _ volumeExistenceChecker = &manifestDriver{}
+var _ csiCallRecorder = &manifestDriver{}
 
 func (m *manifestDriver) GetDriverInfo() *testsuites.DriverInfo {
 	return &m.driverInfo
@@ -206,10 +401,27 @@ func (m *manifestDriver) GetDynamicProvisionStorageClass(fsType string) *storage
 
 	sc, ok := items[0].(*storagev1.StorageClass)
 	Expect(ok).To(BeTrue(), "storage class from %s", m.scManifest)
+	labelItem(sc)
+	if len(m.createdSecrets) > 0 {
+		if sc.Parameters == nil {
+			sc.Parameters = map[string]string{}
+		}
+		for key, value := range secretParameters(m.secrets, m.createdSecrets) {
+			sc.Parameters[key] = value
+		}
+	}
 	return sc
 }
 
 func (m *manifestDriver) SkipUnsupportedTest(pattern testpatterns.TestPattern) {
+	m.skipUnsupportedCluster()
+	skipUnlessClusterSupports(fmt.Sprintf("test pattern %q", pattern.Name), "", patternFeatures(pattern)...)
+}
+
+// skipUnsupportedCluster skips the current test if the driver cannot
+// run in the cluster.
+func (m *manifestDriver) skipUnsupportedCluster() {
+	skipUnlessClusterSupports(m.driverInfo.Name+" driver", m.minKubeVersion, m.requiredFeatures...)
 }
 
 func (m *manifestDriver) GetClaimSize() string {
@@ -217,35 +429,282 @@ func (m *manifestDriver) GetClaimSize() string {
 }
 
 func (m *manifestDriver) CreateDriver() {
+	if m.shared != nil {
+		By(fmt.Sprintf("using %s driver in namespace %s", m.driverInfo.Name, m.shared.f.Namespace.Name))
+		m.callsBefore = 0
+		if calls, err := m.getAllCSICalls(); err == nil {
+			m.callsBefore = len(calls)
+		}
+	} else {
+		m.deploy()
+	}
+	f := m.driverInfo.Config.Framework
+
+	m.createdSecrets = nil
+	if len(m.secrets) > 0 {
+		By(fmt.Sprintf("creating secrets for %s driver", m.driverInfo.Name))
+		secrets, cleanupSecrets, err := createSecrets(f, m.secrets)
+		if err != nil {
+			framework.Failf("creating secrets: %v", err)
+		}
+		m.createdSecrets = secrets
+		m.cleanupSecrets = cleanupSecrets
+	}
+
+	m.metrics = startMetricsCollection(m.driverFramework(), m.metricsEndpoints, m.metricsThresholds)
+}
+
+// deploy creates the driver objects, either in the test namespace or,
+// with -csi.share-drivers, in a new namespace where it remains until
+// CleanupSharedDrivers.
+func (m *manifestDriver) deploy() {
 	By(fmt.Sprintf("deploying %s driver", m.driverInfo.Name))
+	defer startPhase(phaseDeployDriver)()
 	if m.beforeEach != nil {
 		m.beforeEach(m)
 	}
-	f := m.driverInfo.Config.Framework
+	if shareDrivers {
+		shared, err := newSharedDeployment(m.driverInfo.Config.Framework)
+		if err != nil {
+			framework.Failf("creating namespace for %s driver: %v", m.driverInfo.Name, err)
+		}
+		m.shared = shared
+	}
 
-	cleanup, err := f.CreateFromManifests(func(item interface{}) error {
-		return utils.PatchCSIDeployment(f, m.finalPatchOptions(), item)
-	},
-		m.manifests...,
-	)
-	m.cleanup = cleanup
+	m.patchedSidecars = map[string]bool{}
+	cleanup, err := m.driverFramework().CreateFromManifests(m.patchItem, m.manifests...)
+	if m.shared != nil {
+		m.shared.cleanup = cleanup
+	} else {
+		m.cleanup = cleanup
+	}
+	if err == nil {
+		err = m.checkSidecarImages()
+	}
 	if err != nil {
+		// Try again in the next test instead of reusing a
+		// broken instance. The namespace still gets removed
+		// by CleanupSharedDrivers.
+		m.shared = nil
 		framework.Failf("deploying csi hostpath driver: %v", err)
 	}
+
+	// Sampling covers the entire lifetime of the instance.
+	resources := startResourceSampling(m.driverFramework(), m.metricsEndpoints)
+	if m.shared != nil {
+		m.shared.resources = resources
+	} else {
+		m.resources = resources
+	}
+}
+
+// checkSidecarImages returns an error if some of the sidecar images
+// that the driver is supposed to be tested with were not found in
+// the manifests.
+func (m *manifestDriver) checkSidecarImages() error {
+	if unused := unusedSidecarImages(m.sidecarVersions, m.patchedSidecars); len(unused) > 0 {
+		return fmt.Errorf("no container uses the images %s from -csi.sidecar-versions or the driver definition", strings.Join(unused, ", "))
+	}
+	return nil
+}
+
+// driverFramework returns the framework instance that determines
+// namespace and unique name of the driver objects.
+func (m *manifestDriver) driverFramework() *framework.Framework {
+	if m.shared != nil {
+		return m.shared.f
+	}
+	return m.driverInfo.Config.Framework
+}
+
+// patchItem applies all driver specific modifications to an object
+// loaded from the manifests, after the framework has patched names
+// and namespaces.
+func (m *manifestDriver) patchItem(item interface{}) error {
+	labelItem(item)
+	for _, image := range patchSidecarImages(m.sidecarVersions, item) {
+		m.patchedSidecars[image] = true
+	}
+	if err := utils.PatchCSIDeployment(m.driverFramework(), m.finalPatchOptions(), item); err != nil {
+		return err
+	}
+	patchCSIProxy(m.proxyOptions(), item)
+	return nil
 }
 
 func (m *manifestDriver) CleanupDriver() {
+	m.resources.finish(testReportDir())
+	m.resources = nil
+	if m.held {
+		// Forget about the instance, the next test deploys
+		// a new one.
+		By(fmt.Sprintf("keeping %s driver", m.driverInfo.Name))
+		if m.shared != nil {
+			m.shared.held = true
+			m.shared = nil
+		}
+		m.held = false
+		m.cleanup = nil
+		m.cleanupSecrets = nil
+		m.createdSecrets = nil
+		return
+	}
+	if m.cleanupSecrets != nil {
+		m.cleanupSecrets()
+		m.cleanupSecrets = nil
+	}
 	if m.cleanup != nil {
 		By(fmt.Sprintf("uninstalling %s driver", m.driverInfo.Name))
+		stop := startPhase(phaseRemoveDriver)
 		m.cleanup()
+		m.cleanup = nil
+		stop()
+	}
+	if len(m.createdSecrets) > 0 {
+		By("checking that secrets were removed")
+		checkSecretsDeleted(m.driverInfo.Config.Framework, m.createdSecrets)
+		m.createdSecrets = nil
+	}
+}
+
+func (m *manifestDriver) CheckReadOnlyPublish(volumeHandle string) error {
+	if m.readOnlyPublishLog == "" {
+		framework.Logf("no log pattern for read-only NodePublishVolume calls of %s driver, not checking", m.driverInfo.Name)
+		return nil
+	}
+	re, err := regexp.Compile(fmt.Sprintf(m.readOnlyPublishLog, regexp.QuoteMeta(volumeHandle)))
+	if err != nil {
+		return err
+	}
+	pod, err := m.getDriverPod()
+	if err != nil {
+		return err
+	}
+	log, err := framework.GetPodLogs(m.driverInfo.Config.Framework.ClientSet, pod.Namespace, pod.Name, m.patchOptions.DriverContainerName)
+	if err != nil {
+		return err
+	}
+	if !re.MatchString(log) {
+		return fmt.Errorf("no read-only NodePublishVolume call for volume %s found in driver log (%q)", volumeHandle, re)
+	}
+	return nil
+}
+
+func (m *manifestDriver) VolumeExists(volumeHandle string) (bool, error) {
+	if m.volumeExistsCommand == "" {
+		return false, errVolumeCheckUnsupported
+	}
+	pod, err := m.getDriverPod()
+	if err != nil {
+		return false, err
+	}
+	f := m.driverInfo.Config.Framework
+	stdout, stderr, err := f.ExecWithOptions(framework.ExecOptions{
+		Command:       []string{"/bin/sh", "-c", fmt.Sprintf(m.volumeExistsCommand, volumeHandle)},
+		Namespace:     pod.Namespace,
+		PodName:       pod.Name,
+		ContainerName: m.patchOptions.DriverContainerName,
+		CaptureStdout: true,
+		CaptureStderr: true,
+	})
+	if err != nil {
+		return false, fmt.Errorf("checking volume %s: %v (stderr: %s)", volumeHandle, err, stderr)
+	}
+	return stdout == "exists", nil
+}
+
+// GetCSICalls returns the calls recorded by the CSI proxy of the
+// driver instance. For a shared driver, only the calls made since the
+// current test started are returned.
+func (m *manifestDriver) GetCSICalls() ([]csiproxy.Call, error) {
+	calls, err := m.getAllCSICalls()
+	if err != nil {
+		return nil, err
+	}
+	// A shared instance also logged the calls of earlier tests.
+	if m.shared != nil && m.callsBefore <= len(calls) {
+		calls = calls[m.callsBefore:]
+	}
+	return calls, nil
+}
+
+// getAllCSICalls returns the calls logged by the CSI proxy since the
+// driver was deployed.
+func (m *manifestDriver) getAllCSICalls() ([]csiproxy.Call, error) {
+	if m.proxyOptions().Image == "" || m.csiEndpoint == "" {
+		return nil, errCSICallsUnsupported
+	}
+	pod, err := m.getDriverPod()
+	if err != nil {
+		return nil, err
+	}
+	log, err := framework.GetPodLogs(m.driverInfo.Config.Framework.ClientSet, pod.Namespace, pod.Name, csiProxyContainerName)
+	if err != nil {
+		return nil, err
+	}
+	return csiproxy.ParseCalls(log)
+}
+
+// getDriverPod returns the pod with the driver container on the node
+// where the test pods run.
+func (m *manifestDriver) getDriverPod() (*v1.Pod, error) {
+	f := m.driverFramework()
+	pods, err := f.ClientSet.CoreV1().Pods(f.Namespace.Name).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if m.driverInfo.Config.ClientNodeName != "" && pod.Spec.NodeName != m.driverInfo.Config.ClientNodeName {
+			continue
+		}
+		for _, c := range pod.Spec.Containers {
+			if c.Name == m.patchOptions.DriverContainerName {
+				return pod, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no pod with container %s found in namespace %s", m.patchOptions.DriverContainerName, f.Namespace.Name)
+}
+
+func (m *manifestDriver) proxyOptions() csiProxyOptions {
+	return csiProxyOptions{
+		Image:               csiProxyImage,
+		DriverContainerName: m.patchOptions.DriverContainerName,
+		Endpoint:            m.csiEndpoint,
+	}
+}
+
+// socketOnNode returns the path of the CSI socket on the node, if the
+// driver container has it in a host path volume.
+func (m *manifestDriver) socketOnNode(pod *v1.Pod) string {
+	if m.csiEndpoint == "" {
+		return ""
+	}
+	dir := path.Dir(m.csiEndpoint)
+	for _, container := range pod.Spec.Containers {
+		if container.Name != m.patchOptions.DriverContainerName {
+			continue
+		}
+		for _, mount := range container.VolumeMounts {
+			if path.Clean(mount.MountPath) != dir {
+				continue
+			}
+			for _, volume := range pod.Spec.Volumes {
+				if volume.Name == mount.Name && volume.HostPath != nil {
+					return path.Join(volume.HostPath.Path, path.Base(m.csiEndpoint))
+				}
+			}
+		}
 	}
+	return ""
 }
 
 func (m *manifestDriver) finalPatchOptions() utils.PatchCSIOptions {
 	o := m.patchOptions
 	// Unique name not available yet when configuring the driver.
 	if strings.HasSuffix(o.NewDriverName, "-") {
-		o.NewDriverName += m.driverInfo.Config.Framework.UniqueName
+		o.NewDriverName += m.driverFramework().UniqueName
 	}
 	return o
 }
@@ -0,0 +1,281 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"k8s.io/kubernetes/test/e2e/framework"
+	"k8s.io/kubernetes/test/e2e/storage/testsuites"
+
+	"github.com/kubernetes-csi/csi-e2e/pkg/csiproxy"
+)
+
+// csiCallRecorder is an optional interface for test drivers which
+// record the CSI calls that they receive, for example with the
+// csi-proxy.
+type csiCallRecorder interface {
+	// GetCSICalls returns all calls received so far, in the order
+	// in which they were received. It returns
+	// errCSICallsUnsupported when the driver instance does not
+	// record calls.
+	GetCSICalls() ([]csiproxy.Call, error)
+}
+
+var errCSICallsUnsupported = errors.New("CSI calls not recorded")
+
+// getCSICalls returns all calls received by the driver. The boolean
+// is false if the driver does not record calls. Other errors fail
+// the test.
+func getCSICalls(driver testsuites.TestDriver) ([]csiproxy.Call, bool) {
+	recorder, ok := driver.(csiCallRecorder)
+	if !ok {
+		return nil, false
+	}
+	calls, err := recorder.GetCSICalls()
+	if err == errCSICallsUnsupported {
+		return nil, false
+	}
+	framework.ExpectNoError(err, "get CSI calls")
+	return calls, true
+}
+
+// getCSICallsForVolume returns the calls for one volume in the order
+// in which they were received. A CreateVolume call is about the
+// volume if it returned the volume handle or, to include failed
+// attempts, used the same name as such a call.
+func getCSICallsForVolume(driver testsuites.TestDriver, volumeHandle string) ([]csiproxy.Call, bool) {
+	calls, ok := getCSICalls(driver)
+	if !ok {
+		return nil, false
+	}
+	return callsForVolume(calls, volumeHandle), true
+}
+
+func callsForVolume(calls []csiproxy.Call, volumeHandle string) []csiproxy.Call {
+	names := map[string]bool{}
+	for _, call := range calls {
+		if call.Method() == "CreateVolume" && call.VolumeID() == volumeHandle {
+			names[createName(call)] = true
+		}
+	}
+	var result []csiproxy.Call
+	for _, call := range calls {
+		if call.VolumeID() == volumeHandle ||
+			call.Method() == "CreateVolume" && names[createName(call)] {
+			result = append(result, call)
+		}
+	}
+	return result
+}
+
+// csiCallViolation describes why a sequence of calls is invalid.
+type csiCallViolation struct {
+	problem string
+	calls   []csiproxy.Call
+}
+
+func (v *csiCallViolation) Error() string {
+	var trace []string
+	for _, call := range v.calls {
+		trace = append(trace, "    "+call.String())
+	}
+	return fmt.Sprintf("%s:\n%s", v.problem, strings.Join(trace, "\n"))
+}
+
+// csiCallCheck verifies one invariant for the calls received by a
+// driver and returns nil if there is no violation.
+type csiCallCheck func(calls []csiproxy.Call) *csiCallViolation
+
+// csiCallInvariants must hold for all calls received by a driver
+// once all volumes created during a test have been deleted again.
+// checkNoRetryAfterFinalError is not among them because the CSI spec
+// allows retrying after such errors and the sidecars and the kubelet
+// do that, for example in tests which expect a failure.
+var csiCallInvariants = []csiCallCheck{
+	checkCreateHasDelete,
+	checkStageBeforePublish,
+	checkNoDuplicateVolumes,
+}
+
+// checkCSICalls runs the checks and returns all violations as one
+// error, nil if there are none.
+func checkCSICalls(calls []csiproxy.Call, checks ...csiCallCheck) error {
+	var violations []string
+	for _, check := range checks {
+		if v := check(calls); v != nil {
+			violations = append(violations, v.Error())
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(violations, "\n"))
+}
+
+// verifyCSICalls checks all invariants for the calls received by the
+// driver, if it records them.
+func verifyCSICalls(driver testsuites.TestDriver) error {
+	calls, ok := getCSICalls(driver)
+	if !ok {
+		return nil
+	}
+	return checkCSICalls(calls, csiCallInvariants...)
+}
+
+// expectValidCSICallsForVolume fails the test if the calls for the
+// volume violate one of the invariants or one of the additional
+// checks, like checkNoRetryAfterFinalError. The volume must have been
+// deleted already.
+func expectValidCSICallsForVolume(driver testsuites.TestDriver, volumeHandle string, checks ...csiCallCheck) {
+	calls, ok := getCSICallsForVolume(driver, volumeHandle)
+	if !ok {
+		framework.Logf("CSI calls not recorded for driver %s, not checking them", driver.GetDriverInfo().Name)
+		return
+	}
+	checks = append(checks, csiCallInvariants...)
+	framework.ExpectNoError(checkCSICalls(calls, checks...), "CSI calls for volume %s", volumeHandle)
+}
+
+// checkCreateHasDelete verifies that each successfully created volume
+// was also deleted.
+func checkCreateHasDelete(calls []csiproxy.Call) *csiCallViolation {
+	created := map[string]int{}
+	for i, call := range calls {
+		if call.Code != codes.OK.String() {
+			continue
+		}
+		switch call.Method() {
+		case "CreateVolume":
+			if _, ok := created[call.VolumeID()]; !ok {
+				created[call.VolumeID()] = i
+			}
+		case "DeleteVolume":
+			delete(created, call.VolumeID())
+		}
+	}
+	for i, call := range calls {
+		if first, ok := created[call.VolumeID()]; ok && first == i {
+			return &csiCallViolation{
+				problem: fmt.Sprintf("volume %s created, but not deleted", call.VolumeID()),
+				calls:   callsForVolume(calls, call.VolumeID()),
+			}
+		}
+	}
+	return nil
+}
+
+// checkStageBeforePublish verifies that NodePublishVolume with a
+// staging path is only called after NodeStageVolume succeeded for
+// that path and before NodeUnstageVolume. Drivers without staging
+// support get called without staging path.
+func checkStageBeforePublish(calls []csiproxy.Call) *csiCallViolation {
+	staged := map[string]bool{}
+	for i, call := range calls {
+		path, _ := call.Request["staging_target_path"].(string)
+		key := call.VolumeID() + ":" + path
+		switch call.Method() {
+		case "NodeStageVolume":
+			if call.Code == codes.OK.String() {
+				staged[key] = true
+			}
+		case "NodeUnstageVolume":
+			if call.Code == codes.OK.String() {
+				delete(staged, key)
+			}
+		case "NodePublishVolume":
+			if path != "" && !staged[key] {
+				return &csiCallViolation{
+					problem: fmt.Sprintf("volume %s published without being staged at %s", call.VolumeID(), path),
+					calls:   callsForVolume(calls[:i+1], call.VolumeID()),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// finalCodes are the error codes which indicate that repeating the
+// same request cannot succeed.
+var finalCodes = map[string]bool{
+	codes.InvalidArgument.String(): true,
+	codes.AlreadyExists.String():   true,
+	codes.Unimplemented.String():   true,
+}
+
+// checkNoRetryAfterFinalError verifies that a request is not repeated
+// after the driver rejected it with a final error. This is only
+// expected for volumes where the test knows that nothing retries the
+// request, therefore test suites must ask for this check explicitly.
+func checkNoRetryAfterFinalError(calls []csiproxy.Call) *csiCallViolation {
+	failed := map[string]int{}
+	for i, call := range calls {
+		key := requestKey(call)
+		if first, ok := failed[key]; ok {
+			return &csiCallViolation{
+				problem: fmt.Sprintf("%s repeated after final error %s", call.Method(), calls[first].Code),
+				calls:   []csiproxy.Call{calls[first], call},
+			}
+		}
+		if finalCodes[call.Code] {
+			failed[key] = i
+		}
+	}
+	return nil
+}
+
+// checkNoDuplicateVolumes verifies that repeated CreateVolume calls
+// with the same name return the same volume.
+func checkNoDuplicateVolumes(calls []csiproxy.Call) *csiCallViolation {
+	volumes := map[string]string{}
+	for _, call := range calls {
+		if call.Method() != "CreateVolume" || call.Code != codes.OK.String() {
+			continue
+		}
+		name := createName(call)
+		if volumeID, ok := volumes[name]; ok && volumeID != call.VolumeID() {
+			var creates []csiproxy.Call
+			for _, c := range calls {
+				if c.Method() == "CreateVolume" && createName(c) == name {
+					creates = append(creates, c)
+				}
+			}
+			return &csiCallViolation{
+				problem: fmt.Sprintf("CreateVolume for %s returned volumes %s and %s", name, volumeID, call.VolumeID()),
+				calls:   creates,
+			}
+		}
+		volumes[name] = call.VolumeID()
+	}
+	return nil
+}
+
+func createName(call csiproxy.Call) string {
+	name, _ := call.Request["name"].(string)
+	return name
+}
+
+// requestKey identifies identical requests. Only the recorded fields
+// are compared.
+func requestKey(call csiproxy.Call) string {
+	data, _ := json.Marshal(call.Request)
+	return call.FullMethod + " " + string(data)
+}
@@ -0,0 +1,255 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/kubernetes-csi/csi-e2e/pkg/csiproxy"
+)
+
+func createCall(name, volumeID, code string) csiproxy.Call {
+	call := csiproxy.Call{
+		FullMethod: "/csi.v1.Controller/CreateVolume",
+		Code:       code,
+		Request:    map[string]interface{}{"name": name},
+	}
+	if volumeID != "" {
+		call.Response = map[string]interface{}{
+			"volume": map[string]interface{}{"volume_id": volumeID},
+		}
+	}
+	return call
+}
+
+func deleteCall(volumeID, code string) csiproxy.Call {
+	return csiproxy.Call{
+		FullMethod: "/csi.v1.Controller/DeleteVolume",
+		Code:       code,
+		Request:    map[string]interface{}{"volume_id": volumeID},
+	}
+}
+
+func nodeCall(method, volumeID, stagingPath, code string) csiproxy.Call {
+	request := map[string]interface{}{"volume_id": volumeID}
+	if stagingPath != "" {
+		request["staging_target_path"] = stagingPath
+	}
+	return csiproxy.Call{
+		FullMethod: "/csi.v1.Node/" + method,
+		Code:       code,
+		Request:    request,
+	}
+}
+
+func TestCSICallChecks(t *testing.T) {
+	testcases := []struct {
+		name      string
+		check     csiCallCheck
+		calls     []csiproxy.Call
+		violation string
+	}{
+		{
+			name:  "no calls",
+			check: checkCreateHasDelete,
+		},
+		{
+			name:  "create and delete",
+			check: checkCreateHasDelete,
+			calls: []csiproxy.Call{
+				createCall("pvc-1", "vol-1", "OK"),
+				deleteCall("vol-1", "OK"),
+			},
+		},
+		{
+			name:  "failed create",
+			check: checkCreateHasDelete,
+			calls: []csiproxy.Call{
+				createCall("pvc-1", "", "ResourceExhausted"),
+			},
+		},
+		{
+			name:  "create without delete",
+			check: checkCreateHasDelete,
+			calls: []csiproxy.Call{
+				createCall("pvc-1", "vol-1", "OK"),
+				createCall("pvc-2", "vol-2", "OK"),
+				deleteCall("vol-1", "OK"),
+			},
+			violation: "volume vol-2 created, but not deleted",
+		},
+		{
+			name:  "failed delete",
+			check: checkCreateHasDelete,
+			calls: []csiproxy.Call{
+				createCall("pvc-1", "vol-1", "OK"),
+				deleteCall("vol-1", "Internal"),
+			},
+			violation: "volume vol-1 created, but not deleted",
+		},
+		{
+			name:  "retried delete",
+			check: checkCreateHasDelete,
+			calls: []csiproxy.Call{
+				createCall("pvc-1", "vol-1", "OK"),
+				deleteCall("vol-1", "Internal"),
+				deleteCall("vol-1", "OK"),
+			},
+		},
+		{
+			name:  "stage, publish, unstage",
+			check: checkStageBeforePublish,
+			calls: []csiproxy.Call{
+				nodeCall("NodeStageVolume", "vol-1", "/staging", "OK"),
+				nodeCall("NodePublishVolume", "vol-1", "/staging", "OK"),
+				nodeCall("NodeUnpublishVolume", "vol-1", "", "OK"),
+				nodeCall("NodeUnstageVolume", "vol-1", "/staging", "OK"),
+			},
+		},
+		{
+			name:  "publish without staging support",
+			check: checkStageBeforePublish,
+			calls: []csiproxy.Call{
+				nodeCall("NodePublishVolume", "vol-1", "", "OK"),
+			},
+		},
+		{
+			name:  "publish without stage",
+			check: checkStageBeforePublish,
+			calls: []csiproxy.Call{
+				nodeCall("NodePublishVolume", "vol-1", "/staging", "OK"),
+			},
+			violation: "volume vol-1 published without being staged at /staging",
+		},
+		{
+			name:  "publish after failed stage",
+			check: checkStageBeforePublish,
+			calls: []csiproxy.Call{
+				nodeCall("NodeStageVolume", "vol-1", "/staging", "Internal"),
+				nodeCall("NodePublishVolume", "vol-1", "/staging", "OK"),
+			},
+			violation: "volume vol-1 published without being staged at /staging",
+		},
+		{
+			name:  "publish after unstage",
+			check: checkStageBeforePublish,
+			calls: []csiproxy.Call{
+				nodeCall("NodeStageVolume", "vol-1", "/staging", "OK"),
+				nodeCall("NodeUnstageVolume", "vol-1", "/staging", "OK"),
+				nodeCall("NodePublishVolume", "vol-1", "/staging", "OK"),
+			},
+			violation: "volume vol-1 published without being staged at /staging",
+		},
+		{
+			name:  "publish with other staging path",
+			check: checkStageBeforePublish,
+			calls: []csiproxy.Call{
+				nodeCall("NodeStageVolume", "vol-1", "/staging-1", "OK"),
+				nodeCall("NodePublishVolume", "vol-1", "/staging-2", "OK"),
+			},
+			violation: "volume vol-1 published without being staged at /staging-2",
+		},
+		{
+			name:  "publish with staging path of other volume",
+			check: checkStageBeforePublish,
+			calls: []csiproxy.Call{
+				nodeCall("NodeStageVolume", "vol-1", "/staging", "OK"),
+				nodeCall("NodePublishVolume", "vol-2", "/staging", "OK"),
+			},
+			violation: "volume vol-2 published without being staged at /staging",
+		},
+		{
+			name:  "idempotent create",
+			check: checkNoDuplicateVolumes,
+			calls: []csiproxy.Call{
+				createCall("pvc-1", "vol-1", "OK"),
+				createCall("pvc-1", "vol-1", "OK"),
+				createCall("pvc-2", "vol-2", "OK"),
+			},
+		},
+		{
+			name:  "create after failure",
+			check: checkNoDuplicateVolumes,
+			calls: []csiproxy.Call{
+				createCall("pvc-1", "", "DeadlineExceeded"),
+				createCall("pvc-1", "vol-1", "OK"),
+			},
+		},
+		{
+			name:  "duplicate volume",
+			check: checkNoDuplicateVolumes,
+			calls: []csiproxy.Call{
+				createCall("pvc-1", "vol-1", "OK"),
+				createCall("pvc-2", "vol-2", "OK"),
+				createCall("pvc-1", "vol-3", "OK"),
+			},
+			violation: "CreateVolume for pvc-1 returned volumes vol-1 and vol-3",
+		},
+		{
+			name:  "retry after transient error",
+			check: checkNoRetryAfterFinalError,
+			calls: []csiproxy.Call{
+				createCall("pvc-1", "", "Unavailable"),
+				createCall("pvc-1", "vol-1", "OK"),
+			},
+		},
+		{
+			name:  "different request after final error",
+			check: checkNoRetryAfterFinalError,
+			calls: []csiproxy.Call{
+				createCall("pvc-1", "", "InvalidArgument"),
+				createCall("pvc-2", "vol-2", "OK"),
+			},
+		},
+		{
+			name:  "retry after final error",
+			check: checkNoRetryAfterFinalError,
+			calls: []csiproxy.Call{
+				createCall("pvc-1", "", "InvalidArgument"),
+				createCall("pvc-1", "", "InvalidArgument"),
+			},
+			violation: "CreateVolume repeated after final error InvalidArgument",
+		},
+	}
+
+	for _, tc := range testcases {
+		v := tc.check(tc.calls)
+		switch {
+		case tc.violation == "" && v != nil:
+			t.Errorf("%s: unexpected violation: %v", tc.name, v)
+		case tc.violation != "" && v == nil:
+			t.Errorf("%s: expected violation %q, got none", tc.name, tc.violation)
+		case v != nil && v.problem != tc.violation:
+			t.Errorf("%s: expected violation %q, got %q", tc.name, tc.violation, v.problem)
+		}
+	}
+}
+
+func TestCSICallInvariants(t *testing.T) {
+	// A request that the sidecars retry with backoff until the
+	// test gives up is not a violation by default.
+	calls := []csiproxy.Call{
+		createCall("pvc-1", "", "InvalidArgument"),
+		createCall("pvc-1", "", "InvalidArgument"),
+	}
+	if err := checkCSICalls(calls, csiCallInvariants...); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := checkCSICalls(calls, checkNoRetryAfterFinalError); err == nil {
+		t.Error("retry after final error not detected")
+	}
+}
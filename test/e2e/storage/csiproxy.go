@@ -0,0 +1,123 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"flag"
+	"path"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+)
+
+var csiProxyImage string
+
+func init() {
+	flag.StringVar(&csiProxyImage, "csi.proxy-image", "",
+		"If set, the csi-proxy from this image (see 'make csi-proxy-container') gets injected into driver pods and records all CSI calls in the pod logs.")
+}
+
+// csiProxyContainerName is the name of the injected container.
+const csiProxyContainerName = "csi-proxy"
+
+// csiProxyOptions controls how the call recording proxy gets injected
+// into a driver deployment with patchCSIProxy.
+type csiProxyOptions struct {
+	// Image of the proxy. Nothing gets patched when empty.
+	Image string
+	// The container which runs the CSI driver.
+	DriverContainerName string
+	// The path of the socket inside the driver container
+	// on which clients expect the driver, for example
+	// /csi/csi.sock. The proxy listens on this socket while
+	// the driver is moved to a different socket in the same
+	// directory.
+	Endpoint string
+}
+
+// driverEndpoint is where the driver listens when the proxy is
+// active.
+func (o csiProxyOptions) driverEndpoint() string {
+	return path.Join(path.Dir(o.Endpoint), "csi-driver.sock")
+}
+
+// patchCSIProxy injects the proxy into the pod which contains the
+// driver container. The driver's command line and environment are
+// changed so that it listens on a different socket. The proxy runs
+// in its own container with access to the same socket directory.
+func patchCSIProxy(o csiProxyOptions, object interface{}) {
+	if o.Image == "" || o.Endpoint == "" {
+		return
+	}
+
+	switch object := object.(type) {
+	case *appsv1.ReplicaSet:
+		patchCSIProxyPod(o, &object.Spec.Template.Spec)
+	case *appsv1.DaemonSet:
+		patchCSIProxyPod(o, &object.Spec.Template.Spec)
+	case *appsv1.StatefulSet:
+		patchCSIProxyPod(o, &object.Spec.Template.Spec)
+	case *appsv1.Deployment:
+		patchCSIProxyPod(o, &object.Spec.Template.Spec)
+	}
+}
+
+func patchCSIProxyPod(o csiProxyOptions, spec *v1.PodSpec) {
+	var driver *v1.Container
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == o.DriverContainerName {
+			driver = &spec.Containers[i]
+			break
+		}
+	}
+	if driver == nil {
+		return
+	}
+
+	// Both /csi/csi.sock and unix:///csi/csi.sock are covered by
+	// replacing the path.
+	replace := func(s string) string {
+		return strings.Replace(s, o.Endpoint, o.driverEndpoint(), -1)
+	}
+	for i := range driver.Args {
+		driver.Args[i] = replace(driver.Args[i])
+	}
+	for i := range driver.Command {
+		driver.Command[i] = replace(driver.Command[i])
+	}
+	for i := range driver.Env {
+		driver.Env[i].Value = replace(driver.Env[i].Value)
+	}
+
+	var mounts []v1.VolumeMount
+	dir := path.Dir(o.Endpoint)
+	for _, mount := range driver.VolumeMounts {
+		if mount.MountPath == dir {
+			mounts = append(mounts, mount)
+		}
+	}
+	spec.Containers = append(spec.Containers, v1.Container{
+		Name:  csiProxyContainerName,
+		Image: o.Image,
+		Args: []string{
+			"--listen=" + o.Endpoint,
+			"--target=" + o.driverEndpoint(),
+		},
+		VolumeMounts: mounts,
+	})
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+
+	"k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	csiv1alpha1 "k8s.io/csi-api/pkg/apis/csi/v1alpha1"
+	"k8s.io/kubernetes/test/e2e/framework"
+	"k8s.io/kubernetes/test/e2e/framework/ginkgowrapper"
+	"k8s.io/kubernetes/test/e2e/storage/testsuites"
+	"sigs.k8s.io/yaml"
+
+	. "github.com/onsi/ginkgo"
+)
+
+var (
+	failureDumpMutex sync.Mutex
+	failureDump      func()
+)
+
+// setFailureDump sets the function that FailHandler calls for the
+// current test.
+func setFailureDump(dump func()) {
+	failureDumpMutex.Lock()
+	defer failureDumpMutex.Unlock()
+	failureDump = dump
+}
+
+// takeFailureDump returns the function set for the current test and
+// clears it, so that it gets called at most once. It returns nil if
+// FailHandler already called it.
+func takeFailureDump() func() {
+	failureDumpMutex.Lock()
+	defer failureDumpMutex.Unlock()
+	dump := failureDump
+	failureDump = nil
+	return dump
+}
+
+// FailHandler must be registered with gomega.RegisterFailHandler
+// instead of ginkgowrapper.Fail. It dumps the state of the current
+// test when an assertion fails, before the test suites delete their
+// objects in deferred calls and their AfterEach. framework.Failf
+// bypasses gomega; for such failures the state gets dumped after
+// the cleanup.
+func FailHandler(message string, callerSkip ...int) {
+	if dump := takeFailureDump(); dump != nil {
+		dump()
+	}
+	skip := 1
+	if len(callerSkip) > 0 {
+		skip += callerSkip[0]
+	}
+	ginkgowrapper.Fail(message, skip)
+}
+
+// dumpTestState writes the state of all objects that are related to
+// the test namespace or the driver as YAML into the test's report
+// directory or, if there is none, to the GinkgoWriter. With
+// -csi.share-drivers, the events of the driver namespace are also
+// included. It is meant to be called when a test fails and tries to
+// collect as much information as possible, so errors are only
+// logged.
+func dumpTestState(f *framework.Framework, driver testsuites.TestDriver) {
+	driverName := getUniqueDriverName(driver)
+	out := io.Writer(GinkgoWriter)
+	if dir := testReportDir(); dir != "" {
+		filename := path.Join(dir, "state.yaml")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			framework.Logf("ERROR: create directory for %s: %v", filename, err)
+			return
+		}
+		file, err := os.Create(filename)
+		if err != nil {
+			framework.Logf("ERROR: create %s: %v", filename, err)
+			return
+		}
+		defer file.Close()
+		framework.Logf("writing state of namespace %s and driver %s to %s", f.Namespace.Name, driverName, filename)
+		out = file
+	} else {
+		framework.Logf("state of namespace %s and driver %s:", f.Namespace.Name, driverName)
+	}
+
+	cs := f.ClientSet
+	ns := f.Namespace.Name
+	dump := func(what string, items interface{}, err error) {
+		if err != nil {
+			fmt.Fprintf(out, "---\n# %s: ERROR: %v\n", what, err)
+			return
+		}
+		data, err := yaml.Marshal(items)
+		if err != nil {
+			fmt.Fprintf(out, "---\n# %s: ERROR: %v\n", what, err)
+			return
+		}
+		fmt.Fprintf(out, "---\n# %s\n%s", what, data)
+	}
+
+	pvcs, err := cs.CoreV1().PersistentVolumeClaims(ns).List(metav1.ListOptions{})
+	var relatedPVCs []v1.PersistentVolumeClaim
+	if err == nil {
+		relatedPVCs = pvcs.Items
+	}
+	dump("PersistentVolumeClaims", relatedPVCs, err)
+
+	pvNames := map[string]bool{}
+	var relatedPVs []v1.PersistentVolume
+	pvs, err := cs.CoreV1().PersistentVolumes().List(metav1.ListOptions{})
+	if err == nil {
+		for _, pv := range pvs.Items {
+			if pv.Spec.ClaimRef != nil && pv.Spec.ClaimRef.Namespace == ns ||
+				pv.Spec.CSI != nil && pv.Spec.CSI.Driver == driverName ||
+				pv.Annotations[annDynamicallyProvisioned] == driverName {
+				relatedPVs = append(relatedPVs, pv)
+				pvNames[pv.Name] = true
+			}
+		}
+	}
+	dump("PersistentVolumes", relatedPVs, err)
+
+	vas, err := cs.StorageV1().VolumeAttachments().List(metav1.ListOptions{})
+	var relatedVAs []storagev1.VolumeAttachment
+	if err == nil {
+		for _, va := range vas.Items {
+			if va.Spec.Attacher == driverName ||
+				va.Spec.Source.PersistentVolumeName != nil && pvNames[*va.Spec.Source.PersistentVolumeName] {
+				relatedVAs = append(relatedVAs, va)
+			}
+		}
+	}
+	dump("VolumeAttachments", relatedVAs, err)
+
+	scs, err := cs.StorageV1().StorageClasses().List(metav1.ListOptions{})
+	var relatedSCs []storagev1.StorageClass
+	if err == nil {
+		for _, sc := range scs.Items {
+			if sc.Provisioner == driverName {
+				relatedSCs = append(relatedSCs, sc)
+			}
+		}
+	}
+	dump("StorageClasses", relatedSCs, err)
+
+	nodeInfos, err := f.CSIClientSet.CsiV1alpha1().CSINodeInfos().List(metav1.ListOptions{})
+	var relatedNodeInfos []csiv1alpha1.CSINodeInfo
+	if err == nil {
+		for _, nodeInfo := range nodeInfos.Items {
+			for _, driver := range nodeInfo.Spec.Drivers {
+				if driver.Name == driverName {
+					relatedNodeInfos = append(relatedNodeInfos, nodeInfo)
+					break
+				}
+			}
+		}
+	}
+	dump("CSINodeInfos", relatedNodeInfos, err)
+
+	events, err := cs.CoreV1().Events(ns).List(metav1.ListOptions{})
+	var relatedEvents []v1.Event
+	if err == nil {
+		relatedEvents = events.Items
+	}
+	dump("Events", relatedEvents, err)
+
+	if m, ok := driver.(*manifestDriver); ok && m.shared != nil {
+		driverNS := m.shared.f.Namespace.Name
+		events, err := cs.CoreV1().Events(driverNS).List(metav1.ListOptions{})
+		var driverEvents []v1.Event
+		if err == nil {
+			driverEvents = events.Items
+		}
+		dump(fmt.Sprintf("Events in driver namespace %s", driverNS), driverEvents, err)
+	}
+}
+
+// getUniqueDriverName returns the name under which the driver
+// instance of the current test is registered.
+func getUniqueDriverName(driver testsuites.TestDriver) string {
+	if m, ok := driver.(*manifestDriver); ok {
+		return m.finalPatchOptions().NewDriverName
+	}
+	return testsuites.GetUniqueDriverName(driver)
+}
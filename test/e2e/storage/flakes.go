@@ -0,0 +1,270 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/onsi/ginkgo/config"
+	"github.com/onsi/ginkgo/reporters"
+	"github.com/onsi/ginkgo/types"
+)
+
+// Classification of a test after all attempts.
+const (
+	classificationPassed = "passed"
+	classificationFlaky  = "flaky"
+	classificationFailed = "failed"
+)
+
+// flakeResult is the classification of one test.
+type flakeResult struct {
+	Test           string        `json:"test"`
+	Classification string        `json:"classification"`
+	Attempts       int           `json:"attempts"`
+	Failures       []string      `json:"failures,omitempty"`
+	Duration       time.Duration `json:"duration"`
+}
+
+// flakeReporter combines the attempts that Ginkgo makes for a test
+// with -ginkgo.flakeAttempts into a single result for the wrapped
+// reporters. Each attempt runs with a fresh namespace. The driver
+// gets deployed again for each attempt, except with
+// -csi.share-drivers, where all attempts use the same driver
+// instance. Tests which passed after a retry are reported as passed
+// under their original name, to reporters which implement
+// flakyReporter as flaky. Tests which never passed are reported as
+// failed with the failure of each attempt.
+//
+// The classification of each test gets written to
+// csi-flakes_<node>.jsonl. The first node combines those files into
+// csi-flakes.json.
+type flakeReporter struct {
+	dir       string
+	reporters []reporters.Reporter
+	file      *os.File
+	attempts  []types.SpecSummary
+}
+
+var _ reporters.Reporter = &flakeReporter{}
+
+// flakyReporter is implemented by reporters which distinguish
+// tests that passed after a retry from those that passed at the
+// first attempt. The flakeReporter calls flakySpecDidComplete
+// instead of SpecDidComplete for such tests, with the failures of
+// the earlier attempts.
+type flakyReporter interface {
+	flakySpecDidComplete(specSummary *types.SpecSummary, failures []string)
+}
+
+// NewFlakeReporter returns a reporter which classifies tests as
+// passed, flaky or failed and passes one result per test on to the
+// other reporters.
+func NewFlakeReporter(dir string, reporters ...reporters.Reporter) reporters.Reporter {
+	return &flakeReporter{
+		dir:       dir,
+		reporters: reporters,
+	}
+}
+
+func flakeNodeFile(dir string, node int) string {
+	return path.Join(dir, fmt.Sprintf("csi-flakes_%02d.jsonl", node))
+}
+
+func (f *flakeReporter) SpecSuiteWillBegin(config config.GinkgoConfigType, summary *types.SuiteSummary) {
+	err := os.MkdirAll(f.dir, 0755)
+	if err == nil {
+		f.file, err = os.Create(flakeNodeFile(f.dir, config.ParallelNode))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: flake summary: %v\n", err)
+	}
+	for _, r := range f.reporters {
+		r.SpecSuiteWillBegin(config, summary)
+	}
+}
+
+func (f *flakeReporter) BeforeSuiteDidRun(setupSummary *types.SetupSummary) {
+	for _, r := range f.reporters {
+		r.BeforeSuiteDidRun(setupSummary)
+	}
+}
+
+func (f *flakeReporter) SpecWillRun(specSummary *types.SpecSummary) {
+	if len(f.attempts) > 0 {
+		// A retry.
+		return
+	}
+	for _, r := range f.reporters {
+		r.SpecWillRun(specSummary)
+	}
+}
+
+func (f *flakeReporter) SpecDidComplete(specSummary *types.SpecSummary) {
+	f.attempts = append(f.attempts, *specSummary)
+	if specSummary.HasFailureState() && len(f.attempts) < config.GinkgoConfig.FlakeAttempts {
+		// Ginkgo will try again.
+		return
+	}
+	attempts := f.attempts
+	f.attempts = nil
+
+	final := *specSummary
+	classification := classificationPassed
+	switch {
+	case final.HasFailureState():
+		classification = classificationFailed
+		if len(attempts) > 1 {
+			var messages []string
+			for i, attempt := range attempts {
+				messages = append(messages, fmt.Sprintf("attempt #%d: %s", i+1, attempt.Failure.Message))
+			}
+			final.Failure.Message = strings.Join(messages, "\n")
+		}
+	case len(attempts) > 1:
+		classification = classificationFlaky
+	}
+	for _, attempt := range attempts[:len(attempts)-1] {
+		final.RunTime += attempt.RunTime
+	}
+	var failures []string
+	for _, attempt := range attempts {
+		if attempt.HasFailureState() {
+			failures = append(failures, attempt.Failure.Message)
+		}
+	}
+	// Skipped tests are not classified.
+	if final.State == types.SpecStatePassed || final.HasFailureState() {
+		f.record(flakeResult{
+			Test:           strings.Join(specSummary.ComponentTexts[1:], " "),
+			Classification: classification,
+			Attempts:       len(attempts),
+			Failures:       failures,
+			Duration:       final.RunTime,
+		})
+	}
+
+	for _, r := range f.reporters {
+		if flaky, ok := r.(flakyReporter); ok && classification == classificationFlaky {
+			flaky.flakySpecDidComplete(&final, failures)
+			continue
+		}
+		r.SpecDidComplete(&final)
+	}
+}
+
+func (f *flakeReporter) record(result flakeResult) {
+	if f.file == nil {
+		return
+	}
+	data, err := json.Marshal(result)
+	if err == nil {
+		_, err = fmt.Fprintf(f.file, "%s\n", data)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: flake summary: %v\n", err)
+	}
+}
+
+func (f *flakeReporter) AfterSuiteDidRun(setupSummary *types.SetupSummary) {
+	for _, r := range f.reporters {
+		r.AfterSuiteDidRun(setupSummary)
+	}
+}
+
+func (f *flakeReporter) SpecSuiteDidEnd(summary *types.SuiteSummary) {
+	for _, r := range f.reporters {
+		r.SpecSuiteDidEnd(summary)
+	}
+	if f.file == nil {
+		return
+	}
+	f.file.Close()
+	f.file = nil
+	// The first node gets here after all other nodes are done
+	// with their tests.
+	if config.GinkgoConfig.ParallelNode != 1 {
+		return
+	}
+	if err := writeFlakeSummary(f.dir, config.GinkgoConfig.ParallelTotal); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: flake summary: %v\n", err)
+	}
+}
+
+// flakeSummary is the content of csi-flakes.json. Only flaky and
+// failed tests are listed.
+type flakeSummary struct {
+	Passed int           `json:"passed"`
+	Flaky  int           `json:"flaky"`
+	Failed int           `json:"failed"`
+	Tests  []flakeResult `json:"tests"`
+}
+
+func writeFlakeSummary(dir string, nodes int) error {
+	var summary flakeSummary
+	for node := 1; node <= nodes; node++ {
+		if err := readFlakeNodeFile(flakeNodeFile(dir, node), &summary); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(path.Join(dir, "csi-flakes.json"), func(out io.Writer) error {
+		_, err := out.Write(data)
+		return err
+	})
+}
+
+func readFlakeNodeFile(filename string, summary *flakeSummary) error {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var result flakeResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			return fmt.Errorf("%s: %v", filename, err)
+		}
+		switch result.Classification {
+		case classificationPassed:
+			summary.Passed++
+			continue
+		case classificationFlaky:
+			summary.Flaky++
+		case classificationFailed:
+			summary.Failed++
+		}
+		summary.Tests = append(summary.Tests, result)
+	}
+	return scanner.Err()
+}
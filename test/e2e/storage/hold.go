@@ -0,0 +1,170 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/kubernetes/test/e2e/framework"
+	"k8s.io/kubernetes/test/e2e/storage/testsuites"
+)
+
+var (
+	holdOnFailure bool
+	holdWait      bool
+)
+
+func init() {
+	flag.BoolVar(&holdOnFailure, "csi.hold-on-failure", false,
+		"Keep the driver and the namespace of a failed test for debugging and log how to reach them. Unless -csi.hold-wait is also used, this implies -delete-namespace-on-failure=false and setting that flag to true is an error.")
+	flag.BoolVar(&holdWait, "csi.hold-wait", false,
+		"With -csi.hold-on-failure, pause after a failed test until the resume file is touched or the process receives SIGUSR1, then clean up as usual.")
+}
+
+// validateHold checks the hold flags and adapts the framework
+// configuration to them. Held objects must survive the test, so the
+// framework must not delete the namespace either.
+func validateHold() error {
+	if holdWait && !holdOnFailure {
+		return errors.New("-csi.hold-wait requires -csi.hold-on-failure")
+	}
+	if holdOnFailure && !holdWait {
+		if deleteNamespaceOnFailureSet() && framework.TestContext.DeleteNamespaceOnFailure {
+			return errors.New("-csi.hold-on-failure without -csi.hold-wait keeps the namespace of a failed test and conflicts with -delete-namespace-on-failure=true")
+		}
+		framework.TestContext.DeleteNamespaceOnFailure = false
+	}
+	return nil
+}
+
+// deleteNamespaceOnFailureSet returns true if
+// -delete-namespace-on-failure was given on the command line.
+func deleteNamespaceOnFailureSet() bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "delete-namespace-on-failure" {
+			set = true
+		}
+	})
+	return set
+}
+
+// holdDriver is called after a test failed. It logs where the objects
+// of the driver instance can be found and then either waits for the
+// user (-csi.hold-wait) or marks the instance so that CleanupDriver
+// leaves it in place.
+func holdDriver(f *framework.Framework, driver testsuites.TestDriver) {
+	m, ok := driver.(*manifestDriver)
+	if !ok {
+		framework.Logf("%s driver does not support -csi.hold-on-failure, removing it", driver.GetDriverInfo().Name)
+		return
+	}
+
+	var buffer bytes.Buffer
+	w := tabwriter.NewWriter(&buffer, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "test namespace:\t%s\n", f.Namespace.Name)
+	fmt.Fprintf(w, "driver name:\t%s\n", m.finalPatchOptions().NewDriverName)
+	fmt.Fprintf(w, "driver namespace:\t%s\n", m.driverFramework().Namespace.Name)
+	fmt.Fprintf(w, "node:\t%s\n", m.driverInfo.Config.ClientNodeName)
+	if pod, err := m.getDriverPod(); err != nil {
+		fmt.Fprintf(w, "driver pod:\tunknown: %v\n", err)
+	} else {
+		fmt.Fprintf(w, "driver pod:\t%s/%s, container %s\n", pod.Namespace, pod.Name, m.patchOptions.DriverContainerName)
+		if socket := m.socketOnNode(pod); socket != "" {
+			fmt.Fprintf(w, "socket on node:\t%s\n", socket)
+			if m.proxyOptions().Image != "" {
+				// The proxy listens on the normal socket.
+				fmt.Fprintf(w, "driver socket on node:\t%s\n", path.Join(path.Dir(socket), path.Base(m.proxyOptions().driverEndpoint())))
+			}
+		}
+	}
+	unique := m.driverFramework().UniqueName
+	var clusterObjects []string
+	for _, object := range findLeakedObjects(f.ClientSet, f.CSIClientSet, func(name string) bool {
+		return strings.Contains(name, unique)
+	}) {
+		clusterObjects = append(clusterObjects, object.kind+" "+object.name)
+	}
+	if len(clusterObjects) > 0 {
+		fmt.Fprintf(w, "cluster-scoped objects:\t%s\n", strings.Join(clusterObjects, "\n\t"))
+	}
+
+	if !holdWait {
+		fmt.Fprintf(w, "remove with:\tkubectl delete namespace %s", f.Namespace.Name)
+		if m.shared != nil {
+			fmt.Fprintf(w, " %s", m.shared.f.Namespace.Name)
+		}
+		fmt.Fprintln(w)
+		if len(clusterObjects) > 0 {
+			fmt.Fprintf(w, "\tand the cluster-scoped objects\n")
+		}
+		w.Flush()
+		framework.Logf("Test failed, keeping the %s driver:\n%s", m.driverInfo.Name, buffer.String())
+		m.held = true
+		recordHeldName(f.UniqueName)
+		if m.shared != nil {
+			recordHeldName(m.shared.f.UniqueName)
+		}
+		return
+	}
+
+	resumeFile := path.Join(os.TempDir(), "csi-e2e-resume-"+f.Namespace.Name)
+	fmt.Fprintf(w, "resume with:\ttouch %s\n", resumeFile)
+	fmt.Fprintf(w, "\tkill -USR1 %d\n", os.Getpid())
+	w.Flush()
+	framework.Logf("Test failed, pausing before removing the %s driver:\n%s", m.driverInfo.Name, buffer.String())
+	waitForResume(resumeFile)
+	framework.Logf("resuming")
+}
+
+// waitForResume returns once the file was created or its
+// modification time changed, or SIGUSR1 was received.
+func waitForResume(filename string) {
+	modTime := func() time.Time {
+		if info, err := os.Stat(filename); err == nil {
+			return info.ModTime()
+		}
+		return time.Time{}
+	}
+	initial := modTime()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	defer signal.Stop(signals)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-signals:
+			return
+		case <-ticker.C:
+			if !modTime().Equal(initial) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/onsi/ginkgo/reporters"
+	"github.com/onsi/ginkgo/types"
+)
+
+// junitReporter is the Ginkgo JUnit reporter plus the flaky
+// classification: tests which passed after a retry keep their name,
+// so that CI dashboards still find their history, and get the
+// failures of the earlier attempts as system-out.
+type junitReporter struct {
+	*reporters.JUnitReporter
+	filename string
+	// flaky maps test case names to their system-out.
+	flaky map[string]string
+}
+
+var _ reporters.Reporter = &junitReporter{}
+var _ flakyReporter = &junitReporter{}
+
+// NewJUnitReporter returns a reporter which writes the same JUnit
+// file as reporters.NewJUnitReporter. When wrapped by the reporter
+// from NewFlakeReporter, flaky tests are marked in their system-out.
+func NewJUnitReporter(filename string) reporters.Reporter {
+	return &junitReporter{
+		JUnitReporter: reporters.NewJUnitReporter(filename),
+		filename:      filename,
+		flaky:         map[string]string{},
+	}
+}
+
+func (j *junitReporter) flakySpecDidComplete(specSummary *types.SpecSummary, failures []string) {
+	j.JUnitReporter.SpecDidComplete(specSummary)
+	j.flaky[strings.Join(specSummary.ComponentTexts[1:], " ")] = flakyOutput(len(failures)+1, failures)
+}
+
+// flakyOutput describes a test which passed in the last of the
+// given number of attempts.
+func flakyOutput(attempts int, failures []string) string {
+	lines := []string{fmt.Sprintf("%s: passed in attempt #%d of %d", classificationFlaky, attempts, attempts)}
+	for i, failure := range failures {
+		lines = append(lines, fmt.Sprintf("attempt #%d: %s", i+1, failure))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (j *junitReporter) SpecSuiteDidEnd(summary *types.SuiteSummary) {
+	j.JUnitReporter.SpecSuiteDidEnd(summary)
+	if len(j.flaky) == 0 {
+		return
+	}
+	if err := markFlakyTests(j.filename, j.flaky); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: JUnit report: %v\n", err)
+	}
+}
+
+// markFlakyTests sets the system-out of the passed test cases in the
+// JUnit file whose names are in flaky.
+func markFlakyTests(filename string, flaky map[string]string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var suite reporters.JUnitTestSuite
+	if err := xml.Unmarshal(data, &suite); err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	for i := range suite.TestCases {
+		testCase := &suite.TestCases[i]
+		if output, ok := flaky[testCase.Name]; ok && testCase.FailureMessage == nil {
+			testCase.SystemOut = output
+		}
+	}
+	return writeFile(filename, func(out io.Writer) error {
+		if _, err := io.WriteString(out, xml.Header); err != nil {
+			return err
+		}
+		encoder := xml.NewEncoder(out)
+		encoder.Indent("  ", "    ")
+		return encoder.Encode(suite)
+	})
+}
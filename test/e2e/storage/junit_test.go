@@ -0,0 +1,89 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/onsi/ginkgo/config"
+	"github.com/onsi/ginkgo/reporters"
+	"github.com/onsi/ginkgo/types"
+)
+
+func TestJUnitFlakyTests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "junit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := path.Join(dir, "junit.xml")
+
+	oldConfig := config.GinkgoConfig
+	defer func() {
+		config.GinkgoConfig = oldConfig
+	}()
+	config.GinkgoConfig.FlakeAttempts = 2
+	config.GinkgoConfig.ParallelNode = 1
+	config.GinkgoConfig.ParallelTotal = 1
+
+	spec := func(text string, state types.SpecState, message string) *types.SpecSummary {
+		return &types.SpecSummary{
+			ComponentTexts: []string{"", "[Driver: hostpath]", text},
+			State:          state,
+			Failure:        types.SpecFailure{Message: message},
+		}
+	}
+	r := NewFlakeReporter(dir, NewJUnitReporter(filename))
+	r.SpecSuiteWillBegin(config.GinkgoConfig, &types.SuiteSummary{SuiteDescription: "suite"})
+	r.SpecWillRun(spec("flaky", types.SpecStatePending, ""))
+	r.SpecDidComplete(spec("flaky", types.SpecStateFailed, "timed out"))
+	r.SpecWillRun(spec("flaky", types.SpecStatePending, ""))
+	r.SpecDidComplete(spec("flaky", types.SpecStatePassed, ""))
+	r.SpecWillRun(spec("stable", types.SpecStatePending, ""))
+	r.SpecDidComplete(spec("stable", types.SpecStatePassed, ""))
+	r.SpecSuiteDidEnd(&types.SuiteSummary{NumberOfSpecsThatWillBeRun: 2})
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suite reporters.JUnitTestSuite
+	if err := xml.Unmarshal(data, &suite); err != nil {
+		t.Fatal(err)
+	}
+	outputs := map[string]string{}
+	for _, testCase := range suite.TestCases {
+		outputs[testCase.Name] = testCase.SystemOut
+	}
+	expected := map[string]string{
+		"[Driver: hostpath] flaky":  "flaky: passed in attempt #2 of 2\nattempt #1: timed out",
+		"[Driver: hostpath] stable": "",
+	}
+	for name, output := range expected {
+		actual, ok := outputs[name]
+		switch {
+		case !ok:
+			t.Errorf("test case %q missing, got %v", name, outputs)
+		case actual != output:
+			t.Errorf("test case %q: expected system-out %q, got %q", name, output, actual)
+		}
+	}
+}
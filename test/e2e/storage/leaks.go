@@ -0,0 +1,326 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clientset "k8s.io/client-go/kubernetes"
+	csi "k8s.io/csi-api/pkg/client/clientset/versioned"
+	"k8s.io/kubernetes/test/e2e/framework"
+
+	"github.com/onsi/ginkgo/config"
+)
+
+var deleteLeakedObjects bool
+
+func init() {
+	flag.BoolVar(&deleteLeakedObjects, "csi.delete-leaked-objects", false,
+		"Delete cluster-scoped objects that were created by this test run and not removed by the tests themselves.")
+}
+
+// uniqueNamesDir is a directory that is shared by all Ginkgo nodes
+// on the local machine. Each node writes the unique names of its
+// tests into it, one file per node.
+var uniqueNamesDir string
+
+// SetUniqueNamesDir must be called on all nodes before running
+// tests, with a directory that was created for this test run.
+func SetUniqueNamesDir(dir string) {
+	uniqueNamesDir = dir
+}
+
+// recordUniqueName remembers that objects were created with the
+// unique name.
+func recordUniqueName(name string) {
+	recordName("unique-names", name)
+}
+
+// recordHeldName remembers that objects with the unique name were
+// kept on purpose by -csi.hold-on-failure and are not leaked.
+func recordHeldName(name string) {
+	recordName("held-names", name)
+}
+
+func recordName(kind, name string) {
+	if uniqueNamesDir == "" {
+		return
+	}
+	filename := path.Join(uniqueNamesDir, fmt.Sprintf("%s_%02d", kind, config.GinkgoConfig.ParallelNode))
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err == nil {
+		_, err = fmt.Fprintln(file, name)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		framework.Logf("ERROR: recording %s: %v", kind, err)
+	}
+}
+
+func readNames(dir, kind string) ([]string, error) {
+	files, err := filepath.Glob(path.Join(dir, kind+"_*"))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, filename := range files {
+		file, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if name := strings.TrimSpace(scanner.Text()); name != "" {
+				names = append(names, name)
+			}
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// leakedObject is a cluster-scoped object which was left behind.
+type leakedObject struct {
+	kind, name string
+	// remove deletes the object.
+	remove func() error
+}
+
+// CheckLeakedObjects looks for cluster-scoped objects whose name
+// contains one of the unique names used in this test run. It must be
+// called once, after all tests on all nodes have completed. Leaked
+// objects are logged and, if requested, deleted.
+func CheckLeakedObjects() {
+	if uniqueNamesDir == "" {
+		return
+	}
+	names, err := readNames(uniqueNamesDir, "unique-names")
+	if err != nil {
+		framework.Logf("ERROR: reading unique names: %v", err)
+		return
+	}
+	held, err := readNames(uniqueNamesDir, "held-names")
+	if err != nil {
+		framework.Logf("ERROR: reading held names: %v", err)
+		return
+	}
+	if len(held) > 0 {
+		framework.Logf("not checking objects of held tests with unique names: %s", strings.Join(held, ", "))
+		names = sets.NewString(names...).Difference(sets.NewString(held...)).List()
+	}
+	if len(names) == 0 {
+		return
+	}
+	cs, csiClient, err := loadClients()
+	if err != nil {
+		framework.Logf("ERROR: checking for leaked objects: %v", err)
+		return
+	}
+
+	leaked := findLeakedObjects(cs, csiClient, func(name string) bool {
+		for _, unique := range names {
+			if strings.Contains(name, unique) {
+				return true
+			}
+		}
+		return false
+	})
+	if len(leaked) == 0 {
+		framework.Logf("no leaked cluster-scoped objects found")
+		return
+	}
+	var descriptions []string
+	for _, object := range leaked {
+		descriptions = append(descriptions, object.kind+" "+object.name)
+	}
+	framework.Logf("ERROR: leaked cluster-scoped objects:\n    %s", strings.Join(descriptions, "\n    "))
+	if !deleteLeakedObjects {
+		return
+	}
+	removeObjects(leaked)
+}
+
+// removeObjects deletes the objects and logs errors.
+func removeObjects(objects []leakedObject) {
+	for _, object := range objects {
+		framework.Logf("deleting %s %s", object.kind, object.name)
+		if err := object.remove(); err != nil && !apierrs.IsNotFound(err) {
+			framework.Logf("ERROR: deleting %s %s: %v", object.kind, object.name, err)
+		}
+	}
+}
+
+// loadClients creates the clients needed for finding cluster-scoped
+// objects.
+func loadClients() (clientset.Interface, csi.Interface, error) {
+	cs, err := framework.LoadClientset()
+	if err != nil {
+		return nil, nil, err
+	}
+	restConfig, err := framework.LoadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	// csi.storage.k8s.io is based on CRD, which is served only as JSON.
+	restConfig.ContentType = "application/json"
+	csiClient, err := csi.NewForConfig(restConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cs, csiClient, nil
+}
+
+func findLeakedObjects(cs clientset.Interface, csiClient csi.Interface, matches func(name string) bool) []leakedObject {
+	var leaked []leakedObject
+	check := func(kind string, err error) bool {
+		if err == nil {
+			return true
+		}
+		// The CSI CRDs might not be installed.
+		if !apierrs.IsNotFound(err) {
+			framework.Logf("ERROR: listing %ss: %v", kind, err)
+		}
+		return false
+	}
+
+	if items, err := cs.RbacV1().ClusterRoles().List(metav1.ListOptions{}); check("ClusterRole", err) {
+		for _, item := range items.Items {
+			if name := item.Name; matches(name) {
+				leaked = append(leaked, leakedObject{"ClusterRole", name, func() error {
+					return cs.RbacV1().ClusterRoles().Delete(name, nil)
+				}})
+			}
+		}
+	}
+	if items, err := cs.RbacV1().ClusterRoleBindings().List(metav1.ListOptions{}); check("ClusterRoleBinding", err) {
+		for _, item := range items.Items {
+			if name := item.Name; matches(name) {
+				leaked = append(leaked, leakedObject{"ClusterRoleBinding", name, func() error {
+					return cs.RbacV1().ClusterRoleBindings().Delete(name, nil)
+				}})
+			}
+		}
+	}
+	if items, err := cs.StorageV1().StorageClasses().List(metav1.ListOptions{}); check("StorageClass", err) {
+		for _, item := range items.Items {
+			if name := item.Name; matches(name) || matches(item.Provisioner) {
+				leaked = append(leaked, leakedObject{"StorageClass", name, func() error {
+					return cs.StorageV1().StorageClasses().Delete(name, nil)
+				}})
+			}
+		}
+	}
+	if items, err := cs.CoreV1().PersistentVolumes().List(metav1.ListOptions{}); check("PersistentVolume", err) {
+		for _, item := range items.Items {
+			name := item.Name
+			if matches(name) ||
+				item.Spec.CSI != nil && matches(item.Spec.CSI.Driver) ||
+				item.Spec.ClaimRef != nil && matches(item.Spec.ClaimRef.Namespace) {
+				leaked = append(leaked, leakedObject{"PersistentVolume", name, func() error {
+					// The driver which could remove the
+					// volume is gone, so the PV controller
+					// must not wait for it.
+					if _, err := cs.CoreV1().PersistentVolumes().Patch(name, types.MergePatchType, noFinalizers); err != nil {
+						return err
+					}
+					return cs.CoreV1().PersistentVolumes().Delete(name, nil)
+				}})
+			}
+		}
+	}
+	if items, err := cs.StorageV1().VolumeAttachments().List(metav1.ListOptions{}); check("VolumeAttachment", err) {
+		for _, item := range items.Items {
+			name := item.Name
+			if matches(name) || matches(item.Spec.Attacher) {
+				leaked = append(leaked, leakedObject{"VolumeAttachment", name, func() error {
+					// The external-attacher adds a finalizer
+					// which nobody removes once it is gone.
+					if _, err := cs.StorageV1().VolumeAttachments().Patch(name, types.MergePatchType, noFinalizers); err != nil {
+						return err
+					}
+					return cs.StorageV1().VolumeAttachments().Delete(name, nil)
+				}})
+			}
+		}
+	}
+	if items, err := csiClient.CsiV1alpha1().CSIDrivers().List(metav1.ListOptions{}); check("CSIDriver", err) {
+		for _, item := range items.Items {
+			if name := item.Name; matches(name) {
+				leaked = append(leaked, leakedObject{"CSIDriver", name, func() error {
+					return csiClient.CsiV1alpha1().CSIDrivers().Delete(name, nil)
+				}})
+			}
+		}
+	}
+	if items, err := csiClient.CsiV1alpha1().CSINodeInfos().List(metav1.ListOptions{}); check("CSINodeInfo", err) {
+		for _, item := range items.Items {
+			// CSINodeInfo objects are per node, only some
+			// driver entries in them may be stale.
+			for _, driver := range item.Spec.Drivers {
+				if !matches(driver.Name) {
+					continue
+				}
+				nodeName, driverName := item.Name, driver.Name
+				leaked = append(leaked, leakedObject{"CSINodeInfo driver", nodeName + "/" + driverName, func() error {
+					return removeCSINodeInfoDriver(csiClient, nodeName, driverName)
+				}})
+			}
+		}
+	}
+	return leaked
+}
+
+// noFinalizers is a merge patch which removes all finalizers.
+var noFinalizers = []byte(`{"metadata":{"finalizers":null}}`)
+
+func removeCSINodeInfoDriver(csiClient csi.Interface, nodeName, driverName string) error {
+	nodeInfo, err := csiClient.CsiV1alpha1().CSINodeInfos().Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	specDrivers := nodeInfo.Spec.Drivers[:0]
+	for _, driver := range nodeInfo.Spec.Drivers {
+		if driver.Name != driverName {
+			specDrivers = append(specDrivers, driver)
+		}
+	}
+	nodeInfo.Spec.Drivers = specDrivers
+	statusDrivers := nodeInfo.Status.Drivers[:0]
+	for _, driver := range nodeInfo.Status.Drivers {
+		if driver.Name != driverName {
+			statusDrivers = append(statusDrivers, driver)
+		}
+	}
+	nodeInfo.Status.Drivers = statusDrivers
+	_, err = csiClient.CsiV1alpha1().CSINodeInfos().Update(nodeInfo)
+	return err
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/kubernetes/test/e2e/framework"
+	"k8s.io/kubernetes/test/e2e/framework/ginkgowrapper"
+	"k8s.io/kubernetes/test/e2e/storage/testpatterns"
+	"k8s.io/kubernetes/test/e2e/storage/testsuites"
+
+	"github.com/onsi/ginkgo/config"
+	"github.com/onsi/ginkgo/reporters"
+	"github.com/onsi/ginkgo/types"
+)
+
+// knownPatterns are all test patterns that a test name might refer
+// to. The upstream test suites do not reveal their patterns, so
+// they have to be found by name.
+var knownPatterns = []testpatterns.TestPattern{
+	testpatterns.DefaultFsInlineVolume,
+	testpatterns.DefaultFsPreprovisionedPV,
+	testpatterns.DefaultFsDynamicPV,
+	testpatterns.Ext3InlineVolume,
+	testpatterns.Ext3PreprovisionedPV,
+	testpatterns.Ext3DynamicPV,
+	testpatterns.Ext4InlineVolume,
+	testpatterns.Ext4PreprovisionedPV,
+	testpatterns.Ext4DynamicPV,
+	testpatterns.XfsInlineVolume,
+	testpatterns.XfsPreprovisionedPV,
+	testpatterns.XfsDynamicPV,
+	testpatterns.FsVolModePreprovisionedPV,
+	testpatterns.FsVolModeDynamicPV,
+	testpatterns.BlockVolModePreprovisionedPV,
+	testpatterns.BlockVolModeDynamicPV,
+}
+
+// listReporter prints all tests seen during a dry run, together
+// with the outcome that can be predicted without a cluster.
+type listReporter struct {
+	out    io.Writer
+	tests  []matrixResult
+	others []matrixResult
+}
+
+var _ reporters.Reporter = &listReporter{}
+
+// NewListReporter returns a reporter which prints the
+// driver/suite/pattern test matrix to out when the suite ends. It is
+// meant to be used with -ginkgo.dryRun.
+func NewListReporter(out io.Writer) reporters.Reporter {
+	return &listReporter{out: out}
+}
+
+func (l *listReporter) SpecSuiteWillBegin(config config.GinkgoConfigType, summary *types.SuiteSummary) {
+}
+
+func (l *listReporter) BeforeSuiteDidRun(setupSummary *types.SetupSummary) {}
+
+func (l *listReporter) SpecWillRun(specSummary *types.SpecSummary) {}
+
+func (l *listReporter) SpecDidComplete(specSummary *types.SpecSummary) {
+	result, ok := newMatrixResult(specSummary, false)
+	if !ok {
+		result.Test = strings.Join(specSummary.ComponentTexts[1:], " ")
+	}
+	if specSummary.State == types.SpecStatePassed {
+		result.State, result.Reason = predictOutcome(result)
+	} else {
+		result.State, result.Reason = matrixSkipped, "not selected"
+	}
+	if ok {
+		l.tests = append(l.tests, result)
+	} else {
+		l.others = append(l.others, result)
+	}
+}
+
+func (l *listReporter) AfterSuiteDidRun(setupSummary *types.SetupSummary) {}
+
+func (l *listReporter) SpecSuiteDidEnd(summary *types.SuiteSummary) {
+	sort.Slice(l.tests, func(i, j int) bool {
+		a, b := l.tests[i], l.tests[j]
+		switch {
+		case a.Driver != b.Driver:
+			return a.Driver < b.Driver
+		case a.Suite != b.Suite:
+			return a.Suite < b.Suite
+		case a.Pattern != b.Pattern:
+			return a.Pattern < b.Pattern
+		default:
+			return a.Test < b.Test
+		}
+	})
+	sort.Slice(l.others, func(i, j int) bool {
+		return l.others[i].Test < l.others[j].Test
+	})
+	w := tabwriter.NewWriter(l.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "DRIVER\tSUITE\tPATTERN\tTEST\tOUTCOME")
+	for _, test := range l.tests {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", test.Driver, test.Suite, test.Pattern, test.Test, listOutcome(test))
+	}
+	for _, test := range l.others {
+		fmt.Fprintf(w, "-\t-\t-\t%s\t%s\n", test.Test, listOutcome(test))
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: list tests: %v\n", err)
+	}
+}
+
+func listOutcome(result matrixResult) string {
+	if result.Reason == "" {
+		return result.State
+	}
+	return result.State + ": " + result.Reason
+}
+
+// predictOutcome checks whether a test will be skipped because of
+// the capabilities of the driver. Individual tests may still skip
+// themselves at runtime, in particular in the upstream test suites
+// whose own checks cannot be called from here.
+func predictOutcome(result matrixResult) (string, string) {
+	const run = "run"
+	if result.Driver == "" {
+		return run, ""
+	}
+	driver := definedDrivers[result.Driver]
+	if driver == nil {
+		return run, "unknown driver"
+	}
+	var pattern *testpatterns.TestPattern
+	for i := range knownPatterns {
+		if knownPatterns[i].Name == result.Pattern {
+			pattern = &knownPatterns[i]
+			break
+		}
+	}
+	if pattern == nil {
+		return run, "unknown test pattern, may get skipped"
+	}
+
+	for _, initSuite := range csiExtraTestSuites {
+		suite := initSuite()
+		tsInfo := suite.getTestSuiteInfo()
+		if tsInfo.name+tsInfo.featureTag == result.Suite {
+			if reason := skipReason(func() { skipUnsupportedCSITest(suite, driver, *pattern) }); reason != "" {
+				return matrixSkipped, reason
+			}
+			return run, ""
+		}
+	}
+	if reason := skipReason(func() { skipUnsupportedDriverTest(driver, *pattern) }); reason != "" {
+		return matrixSkipped, reason
+	}
+	return run, "test suite may still skip it"
+}
+
+// skipUnsupportedDriverTest contains those steps of the skip check in
+// the testsuites package which do not depend on the test suite.
+func skipUnsupportedDriverTest(driver testsuites.TestDriver, pattern testpatterns.TestPattern) {
+	dInfo := driver.GetDriverInfo()
+
+	var isSupported bool
+	switch pattern.VolType {
+	case testpatterns.InlineVolume:
+		_, isSupported = driver.(testsuites.InlineVolumeTestDriver)
+	case testpatterns.PreprovisionedPV:
+		_, isSupported = driver.(testsuites.PreprovisionedPVTestDriver)
+	case testpatterns.DynamicPV:
+		_, isSupported = driver.(testsuites.DynamicPVTestDriver)
+	}
+	if !isSupported {
+		framework.Skipf("Driver %s doesn't support %v -- skipping", dInfo.Name, pattern.VolType)
+	}
+	if !dInfo.SupportedFsType.Has(pattern.FsType) {
+		framework.Skipf("Driver %s doesn't support %v -- skipping", dInfo.Name, pattern.FsType)
+	}
+	driver.SkipUnsupportedTest(pattern)
+}
+
+// skipReason runs a check which calls framework.Skipf and returns the
+// skip message, or an empty string if the check passed.
+func skipReason(check func()) (reason string) {
+	defer func() {
+		if r := recover(); r != nil {
+			skip, ok := r.(ginkgowrapper.SkipPanic)
+			if !ok {
+				panic(r)
+			}
+			reason = skip.Message
+		}
+	}()
+	check()
+	return ""
+}
@@ -0,0 +1,378 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/onsi/ginkgo/config"
+	"github.com/onsi/ginkgo/reporters"
+	"github.com/onsi/ginkgo/types"
+)
+
+// matrixResult is the outcome of one test for a certain
+// driver/suite/pattern combination.
+type matrixResult struct {
+	Driver   string        `json:"driver"`
+	Suite    string        `json:"suite"`
+	Pattern  string        `json:"pattern"`
+	Test     string        `json:"test"`
+	State    string        `json:"state"`
+	Reason   string        `json:"reason,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Phases   []testPhase   `json:"phases,omitempty"`
+}
+
+const (
+	matrixPassed  = "passed"
+	matrixFlaky   = "flaky"
+	matrixFailed  = "failed"
+	matrixSkipped = "skipped"
+)
+
+// matrixReporter records the result of all storage tests in
+// csi-matrix_<node>.jsonl in the report directory. Once all tests
+// have completed, the reporter on the first node combines those
+// files into csi-matrix.json, csi-matrix.md and csi-matrix.html.
+type matrixReporter struct {
+	dir  string
+	file *os.File
+}
+
+var _ reporters.Reporter = &matrixReporter{}
+var _ flakyReporter = &matrixReporter{}
+
+// NewMatrixReporter returns a reporter which writes the
+// driver/suite/pattern test matrix into the given directory.
+func NewMatrixReporter(dir string) reporters.Reporter {
+	return &matrixReporter{dir: dir}
+}
+
+func matrixNodeFile(dir string, node int) string {
+	return path.Join(dir, fmt.Sprintf("csi-matrix_%02d.jsonl", node))
+}
+
+func (m *matrixReporter) SpecSuiteWillBegin(config config.GinkgoConfigType, summary *types.SuiteSummary) {
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: test matrix: %v\n", err)
+		return
+	}
+	file, err := os.Create(matrixNodeFile(m.dir, config.ParallelNode))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: test matrix: %v\n", err)
+		return
+	}
+	m.file = file
+}
+
+func (m *matrixReporter) BeforeSuiteDidRun(setupSummary *types.SetupSummary) {}
+
+func (m *matrixReporter) SpecWillRun(specSummary *types.SpecSummary) {}
+
+// SpecDidComplete writes the result immediately, because other
+// nodes read it before this node's SpecSuiteDidEnd gets called.
+func (m *matrixReporter) SpecDidComplete(specSummary *types.SpecSummary) {
+	if m.file == nil {
+		return
+	}
+	m.write(specSummary, false)
+}
+
+// flakySpecDidComplete records the test as flaky instead of passed.
+func (m *matrixReporter) flakySpecDidComplete(specSummary *types.SpecSummary, failures []string) {
+	if m.file == nil {
+		return
+	}
+	m.write(specSummary, true)
+}
+
+func (m *matrixReporter) write(specSummary *types.SpecSummary, flaky bool) {
+	result, ok := newMatrixResult(specSummary, flaky)
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(result)
+	if err == nil {
+		_, err = fmt.Fprintf(m.file, "%s\n", data)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: test matrix: %v\n", err)
+	}
+}
+
+func (m *matrixReporter) AfterSuiteDidRun(setupSummary *types.SetupSummary) {}
+
+// SpecSuiteDidEnd on the first node is called after all other nodes
+// have finished their tests.
+func (m *matrixReporter) SpecSuiteDidEnd(summary *types.SuiteSummary) {
+	if m.file == nil {
+		return
+	}
+	m.file.Close()
+	m.file = nil
+	if config.GinkgoConfig.ParallelNode != 1 {
+		return
+	}
+	if err := writeMatrix(m.dir, config.GinkgoConfig.ParallelTotal); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: test matrix: %v\n", err)
+	}
+}
+
+var (
+	driverRE  = regexp.MustCompile(`^\[Driver: ([^\]]*)\](.*)$`)
+	patternRE = regexp.MustCompile(`^\[Testpattern: ([^\]]*)\]\S* (.*)$`)
+)
+
+// matrixDriver returns the driver column for the container that
+// csiVolumes creates for a driver, or false if the text does not
+// belong to such a container.
+func matrixDriver(text string) (string, bool) {
+	driver := driverRE.FindStringSubmatch(text)
+	if driver == nil {
+		return "", false
+	}
+	return driver[1] + driver[2], true
+}
+
+// newMatrixResult extracts driver, suite and pattern from the
+// container names that RunTestSuite and runCSITestSuites create. It
+// returns false for tests which are not part of the matrix. Passed
+// tests are recorded as flaky if they needed more than one attempt.
+func newMatrixResult(spec *types.SpecSummary, flaky bool) (matrixResult, bool) {
+	var result matrixResult
+	texts := spec.ComponentTexts
+	for i := 1; i < len(texts); i++ {
+		driver, ok := matrixDriver(texts[i-1])
+		pattern := patternRE.FindStringSubmatch(texts[i])
+		if !ok || pattern == nil {
+			continue
+		}
+		result.Driver = driver
+		result.Pattern = pattern[1]
+		result.Suite = pattern[2]
+		result.Test = strings.Join(texts[i+1:], " ")
+		break
+	}
+	if result.Driver == "" {
+		return result, false
+	}
+
+	result.Duration = spec.RunTime
+	result.Phases = getTestPhases(strings.Join(texts[1:], " "))
+	switch {
+	case spec.State == types.SpecStatePassed && flaky:
+		result.State = matrixFlaky
+	case spec.State == types.SpecStatePassed:
+		result.State = matrixPassed
+	case spec.HasFailureState():
+		result.State = matrixFailed
+		result.Reason = spec.Failure.Message
+	default:
+		result.State = matrixSkipped
+		result.Reason = spec.Failure.Message
+		if result.Reason == "" {
+			result.Reason = "not selected"
+		}
+	}
+	return result, true
+}
+
+// writeMatrix combines the results from all nodes.
+func writeMatrix(dir string, nodes int) error {
+	var results []matrixResult
+	for node := 1; node <= nodes; node++ {
+		nodeResults, err := readMatrixNodeFile(matrixNodeFile(dir, node))
+		if err != nil {
+			return err
+		}
+		results = append(results, nodeResults...)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Driver != b.Driver {
+			return a.Driver < b.Driver
+		}
+		if a.Suite != b.Suite {
+			return a.Suite < b.Suite
+		}
+		if a.Pattern != b.Pattern {
+			return a.Pattern < b.Pattern
+		}
+		return a.Test < b.Test
+	})
+
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(path.Join(dir, "csi-matrix.json"), func(out io.Writer) error {
+		_, err := out.Write(data)
+		return err
+	}); err != nil {
+		return err
+	}
+	rows := summarizeMatrix(results)
+	if err := writeFile(path.Join(dir, "csi-matrix.md"), func(out io.Writer) error {
+		return writeMatrixMarkdown(out, rows)
+	}); err != nil {
+		return err
+	}
+	return writeFile(path.Join(dir, "csi-matrix.html"), func(out io.Writer) error {
+		return writeMatrixHTML(out, rows)
+	})
+}
+
+func readMatrixNodeFile(filename string) ([]matrixResult, error) {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var results []matrixResult
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var result matrixResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+		results = append(results, result)
+	}
+	return results, scanner.Err()
+}
+
+func writeFile(filename string, write func(out io.Writer) error) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	return file.Close()
+}
+
+// matrixRow summarizes all tests of one driver/suite/pattern
+// combination. It failed if any test failed and passed if at least
+// one test passed.
+type matrixRow struct {
+	driver, suite, pattern string
+	state                  string
+	passed, flaky, failed  int
+	skipped                int
+	reasons                []string
+}
+
+func summarizeMatrix(results []matrixResult) []*matrixRow {
+	var rows []*matrixRow
+	var row *matrixRow
+	for _, result := range results {
+		if row == nil || row.driver != result.Driver || row.suite != result.Suite || row.pattern != result.Pattern {
+			row = &matrixRow{driver: result.Driver, suite: result.Suite, pattern: result.Pattern}
+			rows = append(rows, row)
+		}
+		switch result.State {
+		case matrixPassed:
+			row.passed++
+		case matrixFlaky:
+			row.flaky++
+		case matrixFailed:
+			row.failed++
+		default:
+			row.skipped++
+			found := false
+			for _, reason := range row.reasons {
+				if reason == result.Reason {
+					found = true
+					break
+				}
+			}
+			if !found {
+				row.reasons = append(row.reasons, result.Reason)
+			}
+		}
+	}
+	for _, row := range rows {
+		switch {
+		case row.failed > 0:
+			row.state = matrixFailed
+		case row.flaky > 0:
+			row.state = matrixFlaky
+		case row.passed > 0:
+			row.state = matrixPassed
+		default:
+			row.state = matrixSkipped
+		}
+	}
+	return rows
+}
+
+func (row *matrixRow) details() string {
+	details := fmt.Sprintf("%d passed, %d failed, %d skipped", row.passed, row.failed, row.skipped)
+	if row.flaky > 0 {
+		details = fmt.Sprintf("%d passed, %d flaky, %d failed, %d skipped", row.passed, row.flaky, row.failed, row.skipped)
+	}
+	if len(row.reasons) > 0 {
+		details += ": " + strings.Join(row.reasons, "; ")
+	}
+	return details
+}
+
+func writeMatrixMarkdown(out io.Writer, rows []*matrixRow) error {
+	escape := strings.NewReplacer("|", `\|`, "\n", " ").Replace
+	if _, err := fmt.Fprintf(out, "| Driver | Suite | Pattern | Result | Details |\n|---|---|---|---|---|\n"); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintf(out, "| %s | %s | %s | %s | %s |\n",
+			escape(row.driver), escape(row.suite), escape(row.pattern), row.state, escape(row.details())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeMatrixHTML(out io.Writer, rows []*matrixRow) error {
+	if _, err := fmt.Fprintf(out, "<table>\n<tr><th>Driver</th><th>Suite</th><th>Pattern</th><th>Result</th><th>Details</th></tr>\n"); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintf(out, "<tr class=\"%s\"><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+			row.state,
+			html.EscapeString(row.driver), html.EscapeString(row.suite), html.EscapeString(row.pattern),
+			row.state, html.EscapeString(row.details())); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(out, "</table>\n")
+	return err
+}
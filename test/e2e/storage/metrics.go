@@ -0,0 +1,454 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+	"k8s.io/kubernetes/test/e2e/framework"
+	"k8s.io/kubernetes/test/e2e/storage/testsuites"
+
+	. "github.com/onsi/ginkgo"
+)
+
+// metricsThresholdsFlag can be used multiple times on the command
+// line, each time with one threshold.
+type metricsThresholdsFlag struct {
+	thresholds []metricsThreshold
+}
+
+func (m *metricsThresholdsFlag) String() string {
+	var thresholds []string
+	for _, threshold := range m.thresholds {
+		thresholds = append(thresholds, threshold.String())
+	}
+	return strings.Join(thresholds, " ")
+}
+
+func (m *metricsThresholdsFlag) Set(value string) error {
+	threshold, err := parseMetricsThreshold(value)
+	if err != nil {
+		return err
+	}
+	m.thresholds = append(m.thresholds, threshold)
+	return nil
+}
+
+var (
+	scrapeMetrics        bool
+	metricsThresholdFlag metricsThresholdsFlag
+)
+
+func init() {
+	flag.BoolVar(&scrapeMetrics, "csi.metrics", false,
+		"Scrape Prometheus metrics of drivers which define metrics endpoints at the start and end of each test and store the difference in the report directory.")
+	flag.Var(&metricsThresholdFlag, "csi.metrics-threshold",
+		`Maximum increase of a metric during a test, for example 'csi_sidecar_operations_seconds_count{grpc_status_code!="OK"}<=0'. Can be used multiple times. Implies -csi.metrics.`)
+}
+
+// metricsEndpoint defines where the metrics of a container in the
+// driver deployment can be found.
+type metricsEndpoint struct {
+	// Container which serves the metrics.
+	Container string
+	// Port of the HTTP server.
+	Port int
+	// Path of the metrics, /metrics if empty.
+	Path string
+}
+
+// metricsThreshold limits how much the sum of all samples of a
+// metric with matching labels may grow during a test. For
+// histograms and summaries, the _count, _sum and _bucket samples
+// are separate metrics.
+type metricsThreshold struct {
+	Metric string
+	// Labels which must have (or, when the value starts with !,
+	// not have) a certain value.
+	Labels map[string]string
+	Max    float64
+}
+
+var thresholdRE = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(?:\{(.*)\})?<=(.+)$`)
+var labelRE = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(!?=)\s*"([^"]*)"\s*$`)
+
+// parseMetricsThreshold accepts <metric>[{<label>="<value>",<label>!="<value>"}]<=<max>.
+func parseMetricsThreshold(value string) (metricsThreshold, error) {
+	threshold := metricsThreshold{Labels: map[string]string{}}
+	parts := thresholdRE.FindStringSubmatch(value)
+	if parts == nil {
+		return threshold, fmt.Errorf("expected <metric>[{<label>=\"<value>\",...}]<=<max>, got %q", value)
+	}
+	threshold.Metric = parts[1]
+	if parts[2] != "" {
+		for _, label := range strings.Split(parts[2], ",") {
+			l := labelRE.FindStringSubmatch(label)
+			if l == nil {
+				return threshold, fmt.Errorf("invalid label matcher %q in %q", label, value)
+			}
+			if l[2] == "!=" {
+				threshold.Labels[l[1]] = "!" + l[3]
+			} else {
+				threshold.Labels[l[1]] = l[3]
+			}
+		}
+	}
+	max, err := strconv.ParseFloat(parts[3], 64)
+	if err != nil {
+		return threshold, fmt.Errorf("invalid maximum in %q: %v", value, err)
+	}
+	threshold.Max = max
+	return threshold, nil
+}
+
+func (t metricsThreshold) String() string {
+	var labels []string
+	for name, value := range t.Labels {
+		if strings.HasPrefix(value, "!") {
+			labels = append(labels, fmt.Sprintf("%s!=%q", name, value[1:]))
+		} else {
+			labels = append(labels, fmt.Sprintf("%s=%q", name, value))
+		}
+	}
+	sort.Strings(labels)
+	s := t.Metric
+	if len(labels) > 0 {
+		s += "{" + strings.Join(labels, ",") + "}"
+	}
+	return fmt.Sprintf("%s<=%v", s, t.Max)
+}
+
+// metricsSample is one value, identified by the metric name and
+// its labels.
+type metricsSample struct {
+	Metric string            `json:"metric"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+func (s metricsSample) key() string {
+	var labels []string
+	for name, value := range s.Labels {
+		labels = append(labels, fmt.Sprintf("%s=%q", name, value))
+	}
+	sort.Strings(labels)
+	if len(labels) == 0 {
+		return s.Metric
+	}
+	return s.Metric + "{" + strings.Join(labels, ",") + "}"
+}
+
+func (t metricsThreshold) matches(s metricsSample) bool {
+	if s.Metric != t.Metric {
+		return false
+	}
+	for name, value := range t.Labels {
+		if strings.HasPrefix(value, "!") {
+			if s.Labels[name] == value[1:] {
+				return false
+			}
+		} else if s.Labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// metricsSnapshot contains the samples of all counters, histograms
+// and summaries of one container, keyed by metricsSample.key().
+// Gauges are ignored because their difference has no meaning.
+type metricsSnapshot map[string]metricsSample
+
+// metricsCollector scrapes the metrics endpoints of a driver
+// deployment.
+type metricsCollector struct {
+	f          *framework.Framework
+	endpoints  []metricsEndpoint
+	thresholds []metricsThreshold
+	before     map[string]metricsSnapshot
+}
+
+// startMetricsCollection returns nil if metrics are not to be
+// scraped for the driver. Otherwise it waits for the pods with
+// metrics endpoints to become ready and scrapes all endpoints.
+func startMetricsCollection(f *framework.Framework, endpoints []metricsEndpoint, thresholds []metricsThreshold) *metricsCollector {
+	if !scrapeMetrics && len(metricsThresholdFlag.thresholds) == 0 || len(endpoints) == 0 {
+		return nil
+	}
+	m := &metricsCollector{
+		f:         f,
+		endpoints: endpoints,
+	}
+	m.thresholds = append(m.thresholds, thresholds...)
+	m.thresholds = append(m.thresholds, metricsThresholdFlag.thresholds...)
+	// The driver was just deployed, so its pods may still be
+	// starting.
+	var err error
+	if waitErr := wait.PollImmediate(framework.Poll, metricsStartTimeout, func() (bool, error) {
+		if !m.podsReady() {
+			return false, nil
+		}
+		m.before, err = m.scrape()
+		return err == nil, nil
+	}); waitErr != nil {
+		framework.Logf("metrics not available at the start of the test, thresholds will not be checked for the missing endpoints: %v", err)
+	}
+	return m
+}
+
+// metricsStartTimeout is how long startMetricsCollection waits for
+// the metrics endpoints.
+const metricsStartTimeout = 2 * time.Minute
+
+// podsReady returns true if there is at least one pod with a metrics
+// endpoint and all such pods are ready.
+func (m *metricsCollector) podsReady() bool {
+	pods, err := m.f.ClientSet.CoreV1().Pods(m.f.Namespace.Name).List(metav1.ListOptions{})
+	if err != nil {
+		framework.Logf("listing pods with metrics endpoints: %v", err)
+		return false
+	}
+	found := false
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		for _, endpoint := range m.endpoints {
+			if !hasContainer(*pod, endpoint.Container) {
+				continue
+			}
+			if !podutil.IsPodReady(pod) {
+				return false
+			}
+			found = true
+		}
+	}
+	return found
+}
+
+// scrape returns the snapshots of all endpoints, keyed by
+// <pod>/<container>. Endpoints which cannot be scraped are logged
+// and reported as error.
+func (m *metricsCollector) scrape() (map[string]metricsSnapshot, error) {
+	cs := m.f.ClientSet
+	ns := m.f.Namespace.Name
+	pods, err := cs.CoreV1().Pods(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	snapshots := map[string]metricsSnapshot{}
+	var failed []string
+	for _, pod := range pods.Items {
+		for _, endpoint := range m.endpoints {
+			if !hasContainer(pod, endpoint.Container) {
+				continue
+			}
+			key := pod.Name + "/" + endpoint.Container
+			data, err := getMetrics(cs, ns, pod.Name, endpoint)
+			if err == nil {
+				snapshots[key], err = parseMetrics(bytes.NewReader(data))
+			}
+			if err != nil {
+				framework.Logf("scraping metrics of %s: %v", key, err)
+				failed = append(failed, key)
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return snapshots, fmt.Errorf("scraping metrics failed for %s", strings.Join(failed, ", "))
+	}
+	return snapshots, nil
+}
+
+// getMetrics retrieves the metrics of the endpoint in the pod through
+// the API server proxy.
+func getMetrics(cs clientset.Interface, ns, pod string, endpoint metricsEndpoint) ([]byte, error) {
+	metricsPath := endpoint.Path
+	if metricsPath == "" {
+		metricsPath = "/metrics"
+	}
+	return cs.CoreV1().RESTClient().Get().
+		Namespace(ns).
+		Resource("pods").
+		SubResource("proxy").
+		Name(fmt.Sprintf("%s:%d", pod, endpoint.Port)).
+		Suffix(metricsPath).
+		Do().Raw()
+}
+
+func hasContainer(pod v1.Pod, name string) bool {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func parseMetrics(in io.Reader) (metricsSnapshot, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(in)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := metricsSnapshot{}
+	add := func(name string, metric *dto.Metric, value float64, extra ...string) {
+		sample := metricsSample{Metric: name, Labels: map[string]string{}, Value: value}
+		for _, label := range metric.Label {
+			sample.Labels[label.GetName()] = label.GetValue()
+		}
+		for i := 0; i+1 < len(extra); i += 2 {
+			sample.Labels[extra[i]] = extra[i+1]
+		}
+		snapshot[sample.key()] = sample
+	}
+	for name, family := range families {
+		for _, metric := range family.Metric {
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, metric, metric.GetCounter().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				h := metric.GetHistogram()
+				add(name+"_count", metric, float64(h.GetSampleCount()))
+				add(name+"_sum", metric, h.GetSampleSum())
+				for _, bucket := range h.Bucket {
+					add(name+"_bucket", metric, float64(bucket.GetCumulativeCount()),
+						"le", strconv.FormatFloat(bucket.GetUpperBound(), 'g', -1, 64))
+				}
+			case dto.MetricType_SUMMARY:
+				s := metric.GetSummary()
+				add(name+"_count", metric, float64(s.GetSampleCount()))
+				add(name+"_sum", metric, s.GetSampleSum())
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+// finish scrapes all endpoints again, stores the difference in the
+// report directory (or the GinkgoWriter when there is none) and
+// checks the thresholds. The error describes all problems.
+func (m *metricsCollector) finish() error {
+	if m == nil {
+		return nil
+	}
+	after, err := m.scrape()
+	if err != nil && len(m.thresholds) == 0 {
+		framework.Logf("ignoring: %v", err)
+		err = nil
+	}
+
+	deltas := map[string][]metricsSample{}
+	var keys []string
+	for key, snapshot := range after {
+		if _, ok := m.before[key]; !ok {
+			// Without the counts from the start of the test,
+			// the difference would include everything that
+			// happened before.
+			framework.Logf("no metrics of %s from the start of the test, skipping it", key)
+			continue
+		}
+		keys = append(keys, key)
+		for sampleKey, sample := range snapshot {
+			sample.Value -= m.before[key][sampleKey].Value
+			if sample.Value != 0 {
+				deltas[key] = append(deltas[key], sample)
+			}
+		}
+		sort.Slice(deltas[key], func(i, j int) bool {
+			return deltas[key][i].key() < deltas[key][j].key()
+		})
+	}
+	sort.Strings(keys)
+	m.write(deltas)
+
+	var problems []string
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	for _, threshold := range m.thresholds {
+		for _, key := range keys {
+			var sum float64
+			for _, sample := range deltas[key] {
+				if threshold.matches(sample) {
+					sum += sample.Value
+				}
+			}
+			if sum > threshold.Max {
+				problems = append(problems, fmt.Sprintf("%s: increase of %v exceeds %s", key, sum, threshold))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+func (m *metricsCollector) write(deltas map[string][]metricsSample) {
+	data, err := json.MarshalIndent(deltas, "", "  ")
+	if err != nil {
+		framework.Logf("ERROR: encoding metrics: %v", err)
+		return
+	}
+	dir := testReportDir()
+	if dir == "" {
+		fmt.Fprintf(GinkgoWriter, "metrics changes:\n%s\n", data)
+		return
+	}
+	filename := path.Join(dir, "metrics.json")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		framework.Logf("ERROR: create directory for %s: %v", filename, err)
+		return
+	}
+	if err := writeFile(filename, func(out io.Writer) error {
+		_, err := out.Write(data)
+		return err
+	}); err != nil {
+		framework.Logf("ERROR: %v", err)
+	}
+}
+
+// finishDriverMetrics finishes the metrics collection that was
+// started when deploying the driver, if there is one.
+func finishDriverMetrics(driver testsuites.TestDriver) error {
+	m, ok := driver.(*manifestDriver)
+	if !ok || m.metrics == nil {
+		return nil
+	}
+	By("checking driver metrics")
+	err := m.metrics.finish()
+	m.metrics = nil
+	return err
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kubernetes/test/e2e/framework"
+	"k8s.io/kubernetes/test/e2e/storage/testpatterns"
+	"k8s.io/kubernetes/test/e2e/storage/testsuites"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// unsupportedMountOption is passed to drivers to check how they
+// handle mount options that they don't know about.
+const unsupportedMountOption = "csi-e2e-unsupported-mount-option"
+
+type mountOptionsTestSuite struct {
+	tsInfo csiTestSuiteInfo
+}
+
+var _ csiTestSuite = &mountOptionsTestSuite{}
+
+// initMountOptionsTestSuite returns mountOptionsTestSuite that implements csiTestSuite interface
+func initMountOptionsTestSuite() csiTestSuite {
+	return &mountOptionsTestSuite{
+		tsInfo: csiTestSuiteInfo{
+			name: "mount options",
+			testPatterns: []testpatterns.TestPattern{
+				testpatterns.DefaultFsDynamicPV,
+			},
+		},
+	}
+}
+
+func (m *mountOptionsTestSuite) getTestSuiteInfo() csiTestSuiteInfo {
+	return m.tsInfo
+}
+
+func (m *mountOptionsTestSuite) skipUnsupportedTest(pattern testpatterns.TestPattern, driver testsuites.TestDriver) {
+	dInfo := driver.GetDriverInfo()
+	if dInfo.SupportedMountOption.Len() == 0 && dInfo.RequiredMountOption.Len() == 0 {
+		framework.Skipf("Driver %q does not define supported or required mount options - skipping", dInfo.Name)
+	}
+}
+
+func (m *mountOptionsTestSuite) execTest(driver testsuites.TestDriver, pattern testpatterns.TestPattern) {
+	Context(getCSITestNameStr(m, pattern), func() {
+		BeforeEach(func() {
+			skipUnsupportedCSITest(m, driver, pattern)
+		})
+
+		It("should mount volumes with each supported mount option", func() {
+			dInfo := driver.GetDriverInfo()
+			if dInfo.SupportedMountOption.Len() == 0 {
+				framework.Skipf("Driver %q does not define supported mount options - skipping", dInfo.Name)
+			}
+			for _, option := range dInfo.SupportedMountOption.List() {
+				By(fmt.Sprintf("testing mount option %q", option))
+				testMountOptions(driver, pattern, option)
+			}
+		})
+
+		It("should always mount volumes with the required mount options", func() {
+			dInfo := driver.GetDriverInfo()
+			if dInfo.RequiredMountOption.Len() == 0 {
+				framework.Skipf("Driver %q does not define required mount options - skipping", dInfo.Name)
+			}
+			testMountOptions(driver, pattern)
+		})
+
+		It("should fail to mount volumes with an unsupported mount option", func() {
+			dInfo := driver.GetDriverInfo()
+			if dInfo.SupportedMountOption.Len() == 0 {
+				framework.Skipf("Driver %q does not define supported mount options - skipping", dInfo.Name)
+			}
+			testUnsupportedMountOption(driver, pattern)
+		})
+	})
+}
+
+// mountOptions returns the required mount options plus the
+// additional ones, sorted.
+func mountOptions(dInfo *testsuites.DriverInfo, options ...string) []string {
+	return sets.NewString(options...).Union(dInfo.RequiredMountOption).List()
+}
+
+// testMountOptions provisions a volume with the required mount
+// options plus the additional ones and checks inside a pod that all
+// of them are in effect.
+func testMountOptions(driver testsuites.TestDriver, pattern testpatterns.TestPattern, options ...string) {
+	dInfo := driver.GetDriverInfo()
+	dDriver := getDynamicProvisioningDriver(driver)
+	f := dInfo.Config.Framework
+	cs := f.ClientSet
+
+	sc := dDriver.GetDynamicProvisionStorageClass(pattern.FsType)
+	sc.MountOptions = mountOptions(dInfo, options...)
+	sc, deleteSC := createStorageClass(cs, sc)
+	defer deleteSC()
+
+	claim, pv := createBoundClaim(cs, newClaim(dDriver.GetClaimSize(), f.Namespace.Name, sc))
+	defer deleteClaim(cs, claim, pv)
+	Expect(pv.Spec.MountOptions).To(Equal(sc.MountOptions), "mount options of PV %s", pv.Name)
+
+	By(fmt.Sprintf("checking that /proc/mounts contains %s", strings.Join(sc.MountOptions, ", ")))
+	// The fourth field in /proc/mounts is the comma-separated list of options.
+	command := "options=$(grep ' /mnt/test ' /proc/mounts | awk '{print $4}' | tr , '\\n')"
+	for _, option := range sc.MountOptions {
+		command += fmt.Sprintf(" && ( echo \"$options\" | grep -qx '%s' || ( echo 'missing mount option %s'; false ) )", option, option)
+	}
+	command += " || ( grep ' /mnt/test ' /proc/mounts; false )"
+	runInPod(cs, newVolumeTesterPod(f.Namespace.Name, claim.Name, false, dInfo.Config.ClientNodeName, command))
+}
+
+// testUnsupportedMountOption checks that mounting with an unknown
+// mount option fails with an event instead of silently hanging or
+// ignoring the option.
+func testUnsupportedMountOption(driver testsuites.TestDriver, pattern testpatterns.TestPattern) {
+	dInfo := driver.GetDriverInfo()
+	dDriver := getDynamicProvisioningDriver(driver)
+	f := dInfo.Config.Framework
+	cs := f.ClientSet
+
+	sc := dDriver.GetDynamicProvisionStorageClass(pattern.FsType)
+	sc.MountOptions = mountOptions(dInfo, unsupportedMountOption)
+	sc, deleteSC := createStorageClass(cs, sc)
+	defer deleteSC()
+
+	claim, pv := createBoundClaim(cs, newClaim(dDriver.GetClaimSize(), f.Namespace.Name, sc))
+	defer deleteClaim(cs, claim, pv)
+
+	By("starting a pod which uses the volume")
+	pod := newVolumeTesterPod(f.Namespace.Name, claim.Name, false, dInfo.Config.ClientNodeName, "true")
+	pod, err := cs.CoreV1().Pods(pod.Namespace).Create(pod)
+	framework.ExpectNoError(err, "create pod")
+	defer func() {
+		framework.ExpectNoError(framework.DeletePodWithWait(f, cs, pod))
+	}()
+
+	By("waiting for a FailedMount event")
+	selector := fields.Set{
+		"involvedObject.kind":      "Pod",
+		"involvedObject.name":      pod.Name,
+		"involvedObject.namespace": pod.Namespace,
+		"reason":                   "FailedMount",
+	}.AsSelector().String()
+	err = framework.WaitTimeoutForPodEvent(cs, pod.Name, pod.Namespace, selector, "", framework.PodStartTimeout)
+	framework.ExpectNoError(err, "no FailedMount event for pod %s with mount option %q", pod.Name, unsupportedMountOption)
+
+	pod, err = cs.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+	framework.ExpectNoError(err, "get pod")
+	Expect(pod.Status.Phase).To(Equal(v1.PodPending), "pod %s must not start with mount option %q", pod.Name, unsupportedMountOption)
+}
@@ -0,0 +1,344 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/onsi/ginkgo/config"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/test/e2e/framework"
+
+	. "github.com/onsi/ginkgo"
+)
+
+// Names of the phases that get timed. Provisioning, pods and volume
+// deletion are timed by watchTestPhases for all tests, including
+// those from the Kubernetes test suites. The rest is timed by
+// csi-e2e itself.
+const (
+	phaseCreateNamespace = "create namespace"
+	phaseDeployDriver    = "deploy driver"
+	phaseProvision       = "provision volume"
+	phaseStartPod        = "start pod (attach+mount)"
+	phaseRunPod          = "run pod"
+	phaseDeletePod       = "delete pod"
+	phaseDeleteVolume    = "delete volume"
+	phaseRemoveDriver    = "remove driver"
+	phaseDeleteNamespace = "delete namespace"
+)
+
+// testPhase is the duration of one phase of a test. Phases with the
+// same name can occur more than once per test.
+type testPhase struct {
+	Name     string        `json:"name"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+}
+
+// testPhases records the phases of one test.
+type testPhases struct {
+	Test   string      `json:"test"`
+	Phases []testPhase `json:"phases"`
+}
+
+var (
+	// phasesMutex protects currentPhases, which gets modified by
+	// the test and by watchTestPhases.
+	phasesMutex sync.Mutex
+	// currentPhases are the phases of the running test.
+	currentPhases *testPhases
+	// completedPhases are the phases of all tests that ran on
+	// this node.
+	completedPhases []testPhases
+)
+
+// startTestPhases must be called at the beginning of each test.
+func startTestPhases() {
+	phasesMutex.Lock()
+	defer phasesMutex.Unlock()
+	currentPhases = &testPhases{Test: CurrentGinkgoTestDescription().FullTestText}
+}
+
+// startPhase records the beginning of a phase. The returned function
+// records the end and must be called exactly once.
+func startPhase(name string) func() {
+	start := time.Now()
+	phasesMutex.Lock()
+	phases := currentPhases
+	phasesMutex.Unlock()
+	return func() {
+		addPhase(phases, name, start, time.Now())
+	}
+}
+
+// addPhase adds a phase to the phases of a test, unless that test
+// has completed already.
+func addPhase(phases *testPhases, name string, start, end time.Time) {
+	phasesMutex.Lock()
+	defer phasesMutex.Unlock()
+	if phases == nil || phases != currentPhases {
+		return
+	}
+	phases.Phases = append(phases.Phases, testPhase{
+		Name:     name,
+		Start:    start,
+		Duration: end.Sub(start),
+	})
+}
+
+// watchTestPhases times the phases of the volumes and pods in the
+// test namespace based on their status, from the creation of a claim
+// until it is bound, from the creation of a pod with a claim until
+// it is no longer pending (which includes attaching and mounting the
+// volume), while it runs, from the start of its deletion until it is
+// gone and from the deletion of a claim until its PV is gone. The
+// phases get added to the current test until the context is done.
+func watchTestPhases(ctx context.Context, cs clientset.Interface, ns string) error {
+	claims, err := cs.CoreV1().PersistentVolumeClaims(ns).Watch(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("cannot watch claims: %v", err)
+	}
+	pods, err := cs.CoreV1().Pods(ns).Watch(metav1.ListOptions{})
+	if err != nil {
+		claims.Stop()
+		return fmt.Errorf("cannot watch pods: %v", err)
+	}
+	pvs, err := cs.CoreV1().PersistentVolumes().Watch(metav1.ListOptions{})
+	if err != nil {
+		claims.Stop()
+		pods.Stop()
+		return fmt.Errorf("cannot watch PVs: %v", err)
+	}
+
+	phasesMutex.Lock()
+	w := &phaseWatcher{
+		phases:  currentPhases,
+		started: map[string]time.Time{},
+		done:    map[string]bool{},
+	}
+	phasesMutex.Unlock()
+	go func() {
+		defer claims.Stop()
+		defer pods.Stop()
+		defer pvs.Stop()
+		claimEvents, podEvents, pvEvents := claims.ResultChan(), pods.ResultChan(), pvs.ResultChan()
+		for claimEvents != nil || podEvents != nil || pvEvents != nil {
+			select {
+			case e, ok := <-claimEvents:
+				if !ok {
+					claimEvents = nil
+				} else if claim, ok := e.Object.(*v1.PersistentVolumeClaim); ok {
+					w.claimEvent(e.Type, claim)
+				}
+			case e, ok := <-podEvents:
+				if !ok {
+					podEvents = nil
+				} else if pod, ok := e.Object.(*v1.Pod); ok {
+					w.podEvent(e.Type, pod)
+				}
+			case e, ok := <-pvEvents:
+				if !ok {
+					pvEvents = nil
+				} else if pv, ok := e.Object.(*v1.PersistentVolume); ok && pv.Spec.ClaimRef != nil && pv.Spec.ClaimRef.Namespace == ns {
+					w.pvEvent(e.Type, pv)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// phaseWatcher tracks the phases of objects in a test namespace.
+type phaseWatcher struct {
+	phases *testPhases
+	// started is keyed by <phase>/<object name>.
+	started map[string]time.Time
+	// done contains the keys of phases that ended already.
+	done map[string]bool
+}
+
+func (w *phaseWatcher) begin(phase, object string) {
+	key := phase + "/" + object
+	if _, ok := w.started[key]; !ok && !w.done[key] {
+		w.started[key] = time.Now()
+	}
+}
+
+func (w *phaseWatcher) end(phase, object string) {
+	key := phase + "/" + object
+	if start, ok := w.started[key]; ok {
+		delete(w.started, key)
+		w.done[key] = true
+		addPhase(w.phases, phase, start, time.Now())
+	}
+}
+
+func (w *phaseWatcher) claimEvent(eventType watch.EventType, claim *v1.PersistentVolumeClaim) {
+	switch {
+	case eventType == watch.Deleted || claim.DeletionTimestamp != nil:
+		w.begin(phaseDeleteVolume, claim.Name)
+	case claim.Status.Phase == v1.ClaimBound:
+		w.end(phaseProvision, claim.Name)
+	default:
+		w.begin(phaseProvision, claim.Name)
+	}
+}
+
+func (w *phaseWatcher) podEvent(eventType watch.EventType, pod *v1.Pod) {
+	hasClaim := false
+	for _, volume := range pod.Spec.Volumes {
+		hasClaim = hasClaim || volume.PersistentVolumeClaim != nil
+	}
+	if !hasClaim {
+		return
+	}
+	switch {
+	case eventType == watch.Deleted:
+		w.end(phaseRunPod, pod.Name)
+		w.end(phaseDeletePod, pod.Name)
+	case pod.DeletionTimestamp != nil:
+		w.end(phaseRunPod, pod.Name)
+		w.begin(phaseDeletePod, pod.Name)
+	case pod.Status.Phase == v1.PodPending:
+		w.begin(phaseStartPod, pod.Name)
+	case pod.Status.Phase == v1.PodRunning:
+		w.end(phaseStartPod, pod.Name)
+		w.begin(phaseRunPod, pod.Name)
+	default:
+		w.end(phaseStartPod, pod.Name)
+		w.end(phaseRunPod, pod.Name)
+	}
+}
+
+func (w *phaseWatcher) pvEvent(eventType watch.EventType, pv *v1.PersistentVolume) {
+	if eventType == watch.Deleted {
+		w.end(phaseDeleteVolume, pv.Spec.ClaimRef.Name)
+	}
+}
+
+// finishTestPhases must be called at the end of each test. It logs
+// the phases in the order in which they started.
+func finishTestPhases() {
+	phasesMutex.Lock()
+	defer phasesMutex.Unlock()
+	if currentPhases == nil {
+		return
+	}
+	phases := *currentPhases
+	currentPhases = nil
+	sort.SliceStable(phases.Phases, func(i, j int) bool {
+		return phases.Phases[i].Start.Before(phases.Phases[j].Start)
+	})
+	var durations []string
+	for _, phase := range phases.Phases {
+		durations = append(durations, fmt.Sprintf("%s %v", phase.Name, phase.Duration.Round(time.Millisecond)))
+	}
+	framework.Logf("test phases: %s", strings.Join(durations, ", "))
+	completedPhases = append(completedPhases, phases)
+}
+
+// getTestPhases returns the phases of the test, if they were
+// recorded.
+func getTestPhases(test string) []testPhase {
+	for i := len(completedPhases) - 1; i >= 0; i-- {
+		if completedPhases[i].Test == test {
+			return completedPhases[i].Phases
+		}
+	}
+	return nil
+}
+
+// LogSlowestPhases logs a summary of the phases of all tests. With
+// a report directory, the phases of all Ginkgo nodes are included,
+// otherwise only those of the current node. It must be called after
+// all tests have completed.
+func LogSlowestPhases(reportDir string) {
+	all := completedPhases
+	if reportDir != "" {
+		all = nil
+		for node := 1; node <= config.GinkgoConfig.ParallelTotal; node++ {
+			results, err := readMatrixNodeFile(matrixNodeFile(reportDir, node))
+			if err != nil {
+				framework.Logf("ERROR: reading test phases: %v", err)
+				return
+			}
+			for _, result := range results {
+				all = append(all, testPhases{Test: result.Driver + " " + result.Suite + " " + result.Pattern + " " + result.Test, Phases: result.Phases})
+			}
+		}
+	}
+
+	type phaseSummary struct {
+		name       string
+		count      int
+		total, max time.Duration
+		slowest    string
+	}
+	summaries := map[string]*phaseSummary{}
+	for _, test := range all {
+		for _, phase := range test.Phases {
+			s := summaries[phase.Name]
+			if s == nil {
+				s = &phaseSummary{name: phase.Name}
+				summaries[phase.Name] = s
+			}
+			s.count++
+			s.total += phase.Duration
+			if phase.Duration > s.max {
+				s.max = phase.Duration
+				s.slowest = test.Test
+			}
+		}
+	}
+	if len(summaries) == 0 {
+		return
+	}
+	var sorted []*phaseSummary
+	for _, s := range summaries {
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].total > sorted[j].total
+	})
+
+	var buffer bytes.Buffer
+	w := tabwriter.NewWriter(&buffer, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "PHASE\tCOUNT\tTOTAL\tAVERAGE\tMAX\tSLOWEST TEST\n")
+	for _, s := range sorted {
+		fmt.Fprintf(w, "%s\t%d\t%v\t%v\t%v\t%s\n",
+			s.name, s.count,
+			s.total.Round(time.Second),
+			(s.total / time.Duration(s.count)).Round(time.Millisecond),
+			s.max.Round(time.Millisecond),
+			s.slowest)
+	}
+	w.Flush()
+	framework.Logf("slowest test phases:\n%s", buffer.String())
+}
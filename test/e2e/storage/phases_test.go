@@ -0,0 +1,97 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestPhaseWatcher(t *testing.T) {
+	phases := &testPhases{Test: "test"}
+	currentPhases = phases
+	defer func() {
+		currentPhases = nil
+	}()
+	w := &phaseWatcher{
+		phases:  phases,
+		started: map[string]time.Time{},
+		done:    map[string]bool{},
+	}
+
+	claim := &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "pvc"}}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod"},
+		Spec: v1.PodSpec{
+			Volumes: []v1.Volume{{
+				Name: "volume",
+				VolumeSource: v1.VolumeSource{
+					PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "pvc"},
+				},
+			}},
+		},
+	}
+	otherPod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other"}}
+	pv := &v1.PersistentVolume{
+		Spec: v1.PersistentVolumeSpec{
+			ClaimRef: &v1.ObjectReference{Name: "pvc"},
+		},
+	}
+	now := metav1.Now()
+
+	w.claimEvent(watch.Added, claim.DeepCopy())
+	w.claimEvent(watch.Modified, claim.DeepCopy())
+	claim.Status.Phase = v1.ClaimBound
+	w.claimEvent(watch.Modified, claim.DeepCopy())
+	// Already ended, must not start again.
+	claim.Status.Phase = v1.ClaimLost
+	w.claimEvent(watch.Modified, claim.DeepCopy())
+
+	w.podEvent(watch.Added, pod.DeepCopy())
+	w.podEvent(watch.Added, otherPod.DeepCopy())
+	pod.Status.Phase = v1.PodPending
+	w.podEvent(watch.Modified, pod.DeepCopy())
+	pod.Status.Phase = v1.PodRunning
+	w.podEvent(watch.Modified, pod.DeepCopy())
+	w.podEvent(watch.Modified, otherPod.DeepCopy())
+	pod.DeletionTimestamp = &now
+	w.podEvent(watch.Modified, pod.DeepCopy())
+	w.podEvent(watch.Deleted, pod.DeepCopy())
+
+	claim.DeletionTimestamp = &now
+	w.claimEvent(watch.Modified, claim.DeepCopy())
+	w.claimEvent(watch.Deleted, claim.DeepCopy())
+	w.pvEvent(watch.Modified, pv)
+	w.pvEvent(watch.Deleted, pv)
+
+	var names []string
+	for _, phase := range phases.Phases {
+		names = append(names, phase.Name)
+	}
+	expected := []string{phaseProvision, phaseStartPod, phaseRunPod, phaseDeletePod, phaseDeleteVolume}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected phases %v, got %v", expected, names)
+	}
+	if len(w.started) > 0 {
+		t.Errorf("phases not ended: %v", w.started)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"k8s.io/api/core/v1"
+	"k8s.io/kubernetes/test/e2e/framework"
+	"k8s.io/kubernetes/test/e2e/storage/testpatterns"
+	"k8s.io/kubernetes/test/e2e/storage/testsuites"
+
+	. "github.com/onsi/ginkgo"
+)
+
+// capReadOnlyMany is set for drivers which support provisioning
+// volumes with the ReadOnlyMany access mode.
+const capReadOnlyMany testsuites.Capability = "readOnlyMany"
+
+// readOnlyPublishChecker is an optional interface for test drivers
+// which can verify that a volume was published with readonly=true.
+type readOnlyPublishChecker interface {
+	// CheckReadOnlyPublish returns an error if no NodePublishVolume
+	// call with readonly=true was found for the volume.
+	CheckReadOnlyPublish(volumeHandle string) error
+}
+
+// Shell commands used inside the test pods.
+const (
+	writeDataCommand = "echo 'hello world' > /mnt/test/data"
+	readDataCommand  = "grep 'hello world' /mnt/test/data"
+	// The write must fail with EROFS, any other error is not
+	// what we expect from a read-only mount.
+	writeFailsCommand = "if echo 'hello world' > /mnt/test/new 2>/tmp/error; then echo 'write to read-only volume succeeded'; exit 1; fi; " +
+		"cat /tmp/error; grep -q 'Read-only file system' /tmp/error"
+)
+
+type readOnlyTestSuite struct {
+	tsInfo csiTestSuiteInfo
+}
+
+var _ csiTestSuite = &readOnlyTestSuite{}
+
+// initReadOnlyTestSuite returns readOnlyTestSuite that implements csiTestSuite interface
+func initReadOnlyTestSuite() csiTestSuite {
+	return &readOnlyTestSuite{
+		tsInfo: csiTestSuiteInfo{
+			name: "read-only",
+			testPatterns: []testpatterns.TestPattern{
+				testpatterns.DefaultFsDynamicPV,
+			},
+		},
+	}
+}
+
+func (r *readOnlyTestSuite) getTestSuiteInfo() csiTestSuiteInfo {
+	return r.tsInfo
+}
+
+func (r *readOnlyTestSuite) skipUnsupportedTest(pattern testpatterns.TestPattern, driver testsuites.TestDriver) {
+}
+
+func (r *readOnlyTestSuite) execTest(driver testsuites.TestDriver, pattern testpatterns.TestPattern) {
+	Context(getCSITestNameStr(r, pattern), func() {
+		BeforeEach(func() {
+			skipUnsupportedCSITest(r, driver, pattern)
+		})
+
+		It("should allow reading but not writing with readOnly volume source", func() {
+			dInfo := driver.GetDriverInfo()
+			dDriver := getDynamicProvisioningDriver(driver)
+			f := dInfo.Config.Framework
+			cs := f.ClientSet
+			ns := f.Namespace.Name
+			node := dInfo.Config.ClientNodeName
+
+			sc, deleteSC := createStorageClass(cs, dDriver.GetDynamicProvisionStorageClass(pattern.FsType))
+			defer deleteSC()
+			claim, pv := createBoundClaim(cs, newClaim(dDriver.GetClaimSize(), ns, sc))
+			defer deleteClaim(cs, claim, pv)
+
+			By("writing data with a read-write pod")
+			runInPod(cs, newVolumeTesterPod(ns, claim.Name, false, node, writeDataCommand))
+
+			By("reading data and failing to write with a read-only pod")
+			runInPod(cs, newVolumeTesterPod(ns, claim.Name, true, node, readDataCommand+" && "+writeFailsCommand))
+
+			checkReadOnlyPublish(driver, pv)
+		})
+
+		It("should not allow writing to ReadOnlyMany volumes", func() {
+			dInfo := driver.GetDriverInfo()
+			if !dInfo.Capabilities[capReadOnlyMany] {
+				framework.Skipf("Driver %q does not support ReadOnlyMany - skipping", dInfo.Name)
+			}
+			dDriver := getDynamicProvisioningDriver(driver)
+			f := dInfo.Config.Framework
+			cs := f.ClientSet
+			ns := f.Namespace.Name
+
+			sc, deleteSC := createStorageClass(cs, dDriver.GetDynamicProvisionStorageClass(pattern.FsType))
+			defer deleteSC()
+			claim, pv := createBoundClaim(cs, newClaim(dDriver.GetClaimSize(), ns, sc, v1.ReadOnlyMany))
+			defer deleteClaim(cs, claim, pv)
+
+			By("failing to write with a read-only pod")
+			runInPod(cs, newVolumeTesterPod(ns, claim.Name, true, dInfo.Config.ClientNodeName, writeFailsCommand))
+
+			checkReadOnlyPublish(driver, pv)
+		})
+	})
+}
+
+// checkReadOnlyPublish asks the driver to verify that the volume
+// was published read-only, if the driver supports that.
+func checkReadOnlyPublish(driver testsuites.TestDriver, pv *v1.PersistentVolume) {
+	checker, ok := driver.(readOnlyPublishChecker)
+	if !ok || pv.Spec.CSI == nil {
+		framework.Logf("cannot check NodePublishVolume call for driver %s", driver.GetDriverInfo().Name)
+		return
+	}
+	By("checking that NodePublishVolume was called with readonly=true")
+	framework.ExpectNoError(checker.CheckReadOnlyPublish(pv.Spec.CSI.VolumeHandle))
+}
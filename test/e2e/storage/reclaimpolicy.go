@@ -0,0 +1,231 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/test/e2e/framework"
+	"k8s.io/kubernetes/test/e2e/storage/testpatterns"
+	"k8s.io/kubernetes/test/e2e/storage/testsuites"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// annDynamicallyProvisioned is set by the external-provisioner on
+// all PVs that it creates. The provisioner only deletes volumes
+// which have this annotation with its own name.
+const annDynamicallyProvisioned = "pv.kubernetes.io/provisioned-by"
+
+// volumeExistenceChecker is an optional interface for test drivers
+// which can determine whether a volume exists in the storage backend.
+type volumeExistenceChecker interface {
+	// VolumeExists checks for the volume in the storage backend.
+	// It returns errVolumeCheckUnsupported when the driver instance
+	// cannot do that.
+	VolumeExists(volumeHandle string) (bool, error)
+}
+
+var errVolumeCheckUnsupported = errors.New("checking for volumes not supported")
+
+type reclaimPolicyTestSuite struct {
+	tsInfo csiTestSuiteInfo
+}
+
+var _ csiTestSuite = &reclaimPolicyTestSuite{}
+
+// initReclaimPolicyTestSuite returns reclaimPolicyTestSuite that implements csiTestSuite interface
+func initReclaimPolicyTestSuite() csiTestSuite {
+	return &reclaimPolicyTestSuite{
+		tsInfo: csiTestSuiteInfo{
+			name: "reclaim policy",
+			testPatterns: []testpatterns.TestPattern{
+				testpatterns.DefaultFsDynamicPV,
+			},
+		},
+	}
+}
+
+func (r *reclaimPolicyTestSuite) getTestSuiteInfo() csiTestSuiteInfo {
+	return r.tsInfo
+}
+
+func (r *reclaimPolicyTestSuite) skipUnsupportedTest(pattern testpatterns.TestPattern, driver testsuites.TestDriver) {
+	dInfo := driver.GetDriverInfo()
+	if !dInfo.Capabilities[testsuites.CapPersistence] {
+		framework.Skipf("Driver %q does not provide persistency - skipping", dInfo.Name)
+	}
+}
+
+func (r *reclaimPolicyTestSuite) execTest(driver testsuites.TestDriver, pattern testpatterns.TestPattern) {
+	Context(getCSITestNameStr(r, pattern), func() {
+		BeforeEach(func() {
+			skipUnsupportedCSITest(r, driver, pattern)
+		})
+
+		It("should retain volumes and support re-binding them", func() {
+			testRetainAndRebind(driver, pattern)
+		})
+	})
+}
+
+func testRetainAndRebind(driver testsuites.TestDriver, pattern testpatterns.TestPattern) {
+	dInfo := driver.GetDriverInfo()
+	dDriver := getDynamicProvisioningDriver(driver)
+	f := dInfo.Config.Framework
+	cs := f.ClientSet
+	ns := f.Namespace.Name
+	node := dInfo.Config.ClientNodeName
+
+	sc := dDriver.GetDynamicProvisionStorageClass(pattern.FsType)
+	retain := v1.PersistentVolumeReclaimRetain
+	sc.ReclaimPolicy = &retain
+	sc, deleteSC := createStorageClass(cs, sc)
+	defer deleteSC()
+
+	claim, pv := createBoundClaim(cs, newClaim(dDriver.GetClaimSize(), ns, sc))
+	Expect(pv.Spec.PersistentVolumeReclaimPolicy).To(Equal(v1.PersistentVolumeReclaimRetain))
+	Expect(pv.Spec.CSI).NotTo(BeNil(), "PV %s must be a CSI volume", pv.Name)
+	volumeHandle := pv.Spec.CSI.VolumeHandle
+	// Whatever happens below, the backend volume must be removed again.
+	removed := false
+	defer func(pv *v1.PersistentVolume) {
+		if !removed {
+			removeRetainedVolume(cs, pv)
+		}
+	}(pv)
+
+	By("writing data")
+	runInPod(cs, newVolumeTesterPod(ns, claim.Name, false, node, writeDataCommand))
+
+	By("deleting the claim")
+	deleteClaim(cs, claim, pv)
+	framework.ExpectNoError(framework.WaitForPersistentVolumePhase(v1.VolumeReleased, cs, pv.Name, framework.Poll, framework.PVReclaimingTimeout))
+	checkVolumeExists(driver, volumeHandle, true)
+
+	By("making the PV available again")
+	pvName := pv.Name
+	pv, err := cs.CoreV1().PersistentVolumes().Get(pvName, metav1.GetOptions{})
+	framework.ExpectNoError(err, "get PV %s", pvName)
+	pv.Spec.ClaimRef = nil
+	pv, err = cs.CoreV1().PersistentVolumes().Update(pv)
+	framework.ExpectNoError(err, "clear claimRef of PV %s", pvName)
+	framework.ExpectNoError(framework.WaitForPersistentVolumePhase(v1.VolumeAvailable, cs, pvName, framework.Poll, framework.PVReclaimingTimeout))
+
+	By("binding the PV to a new claim")
+	claim = newClaim(dDriver.GetClaimSize(), ns, sc)
+	claim.Spec.VolumeName = pv.Name
+	claim, boundPV := createBoundClaim(cs, claim)
+	Expect(boundPV.Name).To(Equal(pv.Name), "new claim bound to the old PV")
+
+	By("reading data written through the old claim")
+	runInPod(cs, newVolumeTesterPod(ns, claim.Name, false, node, readDataCommand))
+
+	By("deleting the new claim and the PV")
+	deleteClaim(cs, claim, boundPV)
+	framework.ExpectNoError(framework.WaitForPersistentVolumePhase(v1.VolumeReleased, cs, pv.Name, framework.Poll, framework.PVReclaimingTimeout))
+	framework.ExpectNoError(framework.DeletePersistentVolume(cs, pv.Name))
+	framework.ExpectNoError(framework.WaitForPersistentVolumeDeleted(cs, pv.Name, 5*time.Second, 5*time.Minute))
+	checkVolumeExists(driver, volumeHandle, true)
+
+	By("removing the backend volume")
+	removed = true
+	removeRetainedVolume(cs, boundPV)
+	checkVolumeExists(driver, volumeHandle, false)
+
+	By("checking the CSI calls for the volume")
+	expectValidCSICallsForVolume(driver, volumeHandle)
+}
+
+// removeRetainedVolume ensures that a retained volume gets deleted in
+// the storage backend. If the PV still exists, its reclaim policy is
+// changed to "Delete" and the claim is removed. If the PV was already
+// deleted, it gets recreated with a claim reference to a claim that
+// no longer exists, which the PV controller treats like a deleted
+// claim. The reference must have a UID, otherwise the PV counts as
+// pre-bound and becomes available instead of released. Either way
+// the external-provisioner then deletes the volume.
+func removeRetainedVolume(cs clientset.Interface, pv *v1.PersistentVolume) {
+	current, err := cs.CoreV1().PersistentVolumes().Get(pv.Name, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		if current.Spec.PersistentVolumeReclaimPolicy != v1.PersistentVolumeReclaimDelete {
+			framework.Logf("changing reclaim policy of PV %s to %s", pv.Name, v1.PersistentVolumeReclaimDelete)
+			current.Spec.PersistentVolumeReclaimPolicy = v1.PersistentVolumeReclaimDelete
+			_, err := cs.CoreV1().PersistentVolumes().Update(current)
+			framework.ExpectNoError(err, "update PV %s", pv.Name)
+		}
+		if ref := current.Spec.ClaimRef; ref != nil {
+			err := cs.CoreV1().PersistentVolumeClaims(ref.Namespace).Delete(ref.Name, nil)
+			if err != nil && !apierrs.IsNotFound(err) {
+				framework.ExpectNoError(err, "delete claim %s/%s", ref.Namespace, ref.Name)
+			}
+		}
+	case apierrs.IsNotFound(err):
+		framework.Logf("recreating PV %s with reclaim policy %s", pv.Name, v1.PersistentVolumeReclaimDelete)
+		recreated := &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: pv.Name,
+				Annotations: map[string]string{
+					annDynamicallyProvisioned: pv.Annotations[annDynamicallyProvisioned],
+				},
+			},
+			Spec: *pv.Spec.DeepCopy(),
+		}
+		recreated.Spec.PersistentVolumeReclaimPolicy = v1.PersistentVolumeReclaimDelete
+		if recreated.Spec.ClaimRef == nil {
+			recreated.Spec.ClaimRef = &v1.ObjectReference{
+				Kind:      "PersistentVolumeClaim",
+				Namespace: metav1.NamespaceDefault,
+				Name:      "deleted-claim-" + pv.Name,
+			}
+		}
+		if recreated.Spec.ClaimRef.UID == "" {
+			recreated.Spec.ClaimRef.UID = uuid.NewUUID()
+		}
+		_, err := cs.CoreV1().PersistentVolumes().Create(recreated)
+		framework.ExpectNoError(err, "recreate PV %s", pv.Name)
+	default:
+		framework.ExpectNoError(err, "get PV %s", pv.Name)
+	}
+	framework.ExpectNoError(framework.WaitForPersistentVolumeDeleted(cs, pv.Name, 5*time.Second, 5*time.Minute),
+		"PV %s not deleted by dynamic provisioner", pv.Name)
+}
+
+// checkVolumeExists verifies the state of the volume in the storage
+// backend, if the driver supports that.
+func checkVolumeExists(driver testsuites.TestDriver, volumeHandle string, expected bool) {
+	checker, ok := driver.(volumeExistenceChecker)
+	if !ok {
+		framework.Logf("cannot check backend volume for driver %s", driver.GetDriverInfo().Name)
+		return
+	}
+	exists, err := checker.VolumeExists(volumeHandle)
+	if err == errVolumeCheckUnsupported {
+		framework.Logf("cannot check backend volume for driver %s", driver.GetDriverInfo().Name)
+		return
+	}
+	framework.ExpectNoError(err, "check volume %s", volumeHandle)
+	Expect(exists).To(Equal(expected), "volume %s exists in storage backend", volumeHandle)
+}
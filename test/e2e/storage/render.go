@@ -0,0 +1,122 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kubernetes/test/e2e/framework"
+	"k8s.io/kubernetes/test/e2e/framework/ginkgowrapper"
+	"sigs.k8s.io/yaml"
+)
+
+// RenderManifests writes the manifests of the given drivers as YAML,
+// patched the same way as when deploying them for a test in the
+// namespace. The namespace name is also used as the unique name
+// which gets appended to driver and object names. Without driver
+// names, all drivers are rendered. Nothing gets created in a
+// cluster, therefore changes which depend on the cluster (like the
+// node that the driver runs on) are not applied.
+func RenderManifests(out io.Writer, namespace string, drivers ...string) (finalErr error) {
+	// Loading manifests fails with framework.Failf.
+	defer func() {
+		if r := recover(); r != nil {
+			failure, ok := r.(ginkgowrapper.FailurePanic)
+			if !ok {
+				panic(r)
+			}
+			finalErr = errors.New(failure.Message)
+		}
+	}()
+
+	var names []string
+	for name := range definedDrivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(drivers) == 0 {
+		drivers = names
+	}
+
+	f := &framework.Framework{
+		BaseName:   "csi",
+		UniqueName: namespace,
+		Namespace: &v1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: namespace,
+			},
+		},
+	}
+	for _, name := range drivers {
+		driver, ok := definedDrivers[name]
+		if !ok {
+			return fmt.Errorf("unknown driver %q, must be one of: %s", name, strings.Join(names, ", "))
+		}
+		m, ok := driver.(*manifestDriver)
+		if !ok {
+			return fmt.Errorf("driver %q is not deployed via manifests", name)
+		}
+		if err := m.render(f, name, out); err != nil {
+			return fmt.Errorf("driver %q: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// render writes the patched manifests for a copy of the driver which
+// uses the given framework instance.
+func (m *manifestDriver) render(f *framework.Framework, name string, out io.Writer) error {
+	driver := *m
+	driver.driverInfo.Config.Framework = f
+	driver.patchedSidecars = map[string]bool{}
+	manifests := append([]string{}, driver.manifests...)
+	manifests = append(manifests, driver.scManifest)
+	for _, manifest := range manifests {
+		items, err := f.LoadFromManifests(manifest)
+		if err != nil {
+			return err
+		}
+		if err := f.PatchItems(items...); err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := driver.patchItem(item); err != nil {
+				return err
+			}
+			// The decoded objects have no type information.
+			if object, ok := item.(runtime.Object); ok {
+				if kinds, _, err := scheme.Scheme.ObjectKinds(object); err == nil {
+					object.GetObjectKind().SetGroupVersionKind(kinds[0])
+				}
+			}
+			data, err := yaml.Marshal(item)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "---\n# Driver: %s\n# Source: %s\n%s", name, manifest, data)
+		}
+	}
+	return driver.checkSidecarImages()
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"path"
+	"regexp"
+
+	"k8s.io/kubernetes/test/e2e/framework"
+
+	. "github.com/onsi/ginkgo"
+)
+
+var unsafeFileNameChars = regexp.MustCompile("[^a-zA-Z0-9_-]+")
+
+// maxDirNameLen is below the usual limit of 255 bytes for a single
+// path component and leaves room for a hash suffix.
+const maxDirNameLen = 200
+
+// testReportDir returns the directory inside the report directory
+// for all files that belong to the current test, or an empty string
+// if no report directory was configured. The directory name is
+// derived from the full name of the test. The directory itself is not
+// created.
+func testReportDir() string {
+	if framework.TestContext.ReportDir == "" {
+		return ""
+	}
+	test := CurrentGinkgoTestDescription()
+	dirname := unsafeFileNameChars.ReplaceAllString(test.FullTestText, "_")
+	if len(dirname) > maxDirNameLen {
+		// Long names get truncated. The hash of the full name
+		// keeps them unique.
+		dirname = fmt.Sprintf("%s_%x", dirname[:maxDirNameLen], sha1.Sum([]byte(test.FullTestText)))
+	}
+	return path.Join(framework.TestContext.ReportDir, dirname)
+}
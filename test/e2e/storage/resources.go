@@ -0,0 +1,315 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+	"k8s.io/kubernetes/pkg/master/ports"
+	"k8s.io/kubernetes/test/e2e/framework"
+)
+
+var resourceUsageInterval time.Duration
+
+func init() {
+	flag.DurationVar(&resourceUsageInterval, "csi.resource-usage-interval", 0,
+		"If non-zero, CPU and memory usage of the driver pods get sampled with this interval via the kubelet stats API and stored in the report directory, together with the number of goroutines of containers which have a metrics endpoint.")
+}
+
+// minGrowthSamples is the number of samples needed before continuous
+// memory growth is considered suspicious.
+const minGrowthSamples = 5
+
+// resourceSample is the usage of one container at a certain time.
+type resourceSample struct {
+	Time     time.Time `json:"time"`
+	CPUCores float64   `json:"cpuCores"`
+	// The working set is what the kubelet uses for eviction
+	// decisions, so that is what we track.
+	MemoryBytes uint64 `json:"memoryBytes"`
+}
+
+// goroutineSample is the go_goroutines gauge of one container at a
+// certain time.
+type goroutineSample struct {
+	Time       time.Time `json:"time"`
+	Goroutines int       `json:"goroutines"`
+}
+
+// containerUsage is the time series for one container.
+type containerUsage struct {
+	Samples []resourceSample `json:"samples"`
+	// Only sampled for containers with a metrics endpoint.
+	Goroutines []goroutineSample `json:"goroutines,omitempty"`
+	// MonotonicMemoryGrowth is true if memory usage never
+	// decreased and grew overall, which is a sign of a leak.
+	MonotonicMemoryGrowth bool `json:"monotonicMemoryGrowth"`
+	// MonotonicGoroutineGrowth is the same for the number of
+	// goroutines.
+	MonotonicGoroutineGrowth bool `json:"monotonicGoroutineGrowth,omitempty"`
+}
+
+// resourceSampler periodically samples the usage of all containers
+// in pods that belong to the driver deployment, i.e. pods in the
+// namespace which are owned by some controller. It runs for as long
+// as the driver is deployed, so with -csi.share-drivers there is one
+// time series per container for all tests on a Ginkgo node.
+type resourceSampler struct {
+	cs        clientset.Interface
+	ns        string
+	endpoints []metricsEndpoint
+	interval  time.Duration
+	stop      chan struct{}
+	wg        sync.WaitGroup
+
+	// usage is keyed by <pod>/<container>.
+	usage map[string]*containerUsage
+}
+
+// startResourceSampling returns nil if sampling is disabled. The
+// number of goroutines is read from the metrics endpoints.
+func startResourceSampling(f *framework.Framework, endpoints []metricsEndpoint) *resourceSampler {
+	if resourceUsageInterval <= 0 {
+		return nil
+	}
+	r := &resourceSampler{
+		cs:        f.ClientSet,
+		ns:        f.Namespace.Name,
+		endpoints: endpoints,
+		interval:  resourceUsageInterval,
+		stop:      make(chan struct{}),
+		usage:     map[string]*containerUsage{},
+	}
+	r.wg.Add(1)
+	go r.run()
+	return r
+}
+
+func (r *resourceSampler) run() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.sample()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+func (r *resourceSampler) sample() {
+	pods, err := r.cs.CoreV1().Pods(r.ns).List(metav1.ListOptions{})
+	if err != nil {
+		framework.Logf("resource usage: listing pods: %v", err)
+		return
+	}
+	driverPods := map[string]bool{}
+	nodes := map[string]bool{}
+	for _, pod := range pods.Items {
+		if metav1.GetControllerOf(&pod) != nil && pod.Spec.NodeName != "" {
+			driverPods[pod.Name] = true
+			nodes[pod.Spec.NodeName] = true
+			r.sampleGoroutines(pod)
+		}
+	}
+	for node := range nodes {
+		summary, err := getKubeletStats(r.cs, node)
+		if err != nil {
+			framework.Logf("resource usage: stats from node %s: %v", node, err)
+			continue
+		}
+		for _, pod := range summary.Pods {
+			if pod.PodRef.Namespace != r.ns || !driverPods[pod.PodRef.Name] {
+				continue
+			}
+			for _, container := range pod.Containers {
+				if container.CPU == nil || container.Memory == nil {
+					continue
+				}
+				sample := resourceSample{Time: container.CPU.Time.Time}
+				if container.CPU.UsageNanoCores != nil {
+					sample.CPUCores = float64(*container.CPU.UsageNanoCores) / 1e9
+				}
+				if container.Memory.WorkingSetBytes != nil {
+					sample.MemoryBytes = *container.Memory.WorkingSetBytes
+				}
+				usage := r.containerUsage(pod.PodRef.Name, container.Name)
+				// The kubelet only updates its stats
+				// periodically.
+				if len(usage.Samples) > 0 && usage.Samples[len(usage.Samples)-1].Time.Equal(sample.Time) {
+					continue
+				}
+				usage.Samples = append(usage.Samples, sample)
+			}
+		}
+	}
+}
+
+// sampleGoroutines reads the go_goroutines gauge of all containers in
+// the pod which have a metrics endpoint. Not all binaries export it.
+func (r *resourceSampler) sampleGoroutines(pod v1.Pod) {
+	for _, endpoint := range r.endpoints {
+		if !hasContainer(pod, endpoint.Container) {
+			continue
+		}
+		now := time.Now()
+		data, err := getMetrics(r.cs, r.ns, pod.Name, endpoint)
+		if err != nil {
+			framework.Logf("resource usage: metrics of %s/%s: %v", pod.Name, endpoint.Container, err)
+			continue
+		}
+		var parser expfmt.TextParser
+		families, err := parser.TextToMetricFamilies(bytes.NewReader(data))
+		if err != nil {
+			framework.Logf("resource usage: metrics of %s/%s: %v", pod.Name, endpoint.Container, err)
+			continue
+		}
+		family := families["go_goroutines"]
+		if family == nil || len(family.Metric) == 0 {
+			continue
+		}
+		usage := r.containerUsage(pod.Name, endpoint.Container)
+		usage.Goroutines = append(usage.Goroutines, goroutineSample{
+			Time:       now,
+			Goroutines: int(family.Metric[0].GetGauge().GetValue()),
+		})
+	}
+}
+
+func (r *resourceSampler) containerUsage(pod, container string) *containerUsage {
+	key := pod + "/" + container
+	usage := r.usage[key]
+	if usage == nil {
+		usage = &containerUsage{}
+		r.usage[key] = usage
+	}
+	return usage
+}
+
+func getKubeletStats(cs clientset.Interface, node string) (*stats.Summary, error) {
+	data, err := cs.CoreV1().RESTClient().Get().
+		Resource("nodes").
+		SubResource("proxy").
+		Name(fmt.Sprintf("%s:%d", node, ports.KubeletPort)).
+		Suffix("stats/summary").
+		Do().Raw()
+	if err != nil {
+		return nil, err
+	}
+	var summary stats.Summary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// finish stops sampling, checks for memory and goroutine growth and
+// stores the time series in the given directory or, if it is empty,
+// logs a summary.
+func (r *resourceSampler) finish(dir string) {
+	if r == nil {
+		return
+	}
+	close(r.stop)
+	r.wg.Wait()
+
+	var keys []string
+	for key := range r.usage {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		usage := r.usage[key]
+		var memory, goroutines []float64
+		for _, sample := range usage.Samples {
+			memory = append(memory, float64(sample.MemoryBytes))
+		}
+		for _, sample := range usage.Goroutines {
+			goroutines = append(goroutines, float64(sample.Goroutines))
+		}
+		usage.MonotonicMemoryGrowth = monotonicGrowth(memory)
+		usage.MonotonicGoroutineGrowth = monotonicGrowth(goroutines)
+		if usage.MonotonicMemoryGrowth {
+			framework.Logf("WARNING: memory usage of %s grew continuously from %d to %d bytes in %d samples",
+				key, usage.Samples[0].MemoryBytes, usage.Samples[len(usage.Samples)-1].MemoryBytes, len(usage.Samples))
+		}
+		if usage.MonotonicGoroutineGrowth {
+			framework.Logf("WARNING: goroutines of %s grew continuously from %d to %d in %d samples",
+				key, usage.Goroutines[0].Goroutines, usage.Goroutines[len(usage.Goroutines)-1].Goroutines, len(usage.Goroutines))
+		}
+	}
+
+	if dir == "" {
+		for _, key := range keys {
+			samples := r.usage[key].Samples
+			if len(samples) == 0 {
+				continue
+			}
+			last := samples[len(samples)-1]
+			framework.Logf("resource usage of %s: %d samples, last %.3f cores, %d bytes",
+				key, len(samples), last.CPUCores, last.MemoryBytes)
+		}
+		return
+	}
+	data, err := json.MarshalIndent(r.usage, "", "  ")
+	if err != nil {
+		framework.Logf("ERROR: encoding resource usage: %v", err)
+		return
+	}
+	filename := path.Join(dir, "resource-usage.json")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		framework.Logf("ERROR: create directory for %s: %v", filename, err)
+		return
+	}
+	if err := writeFile(filename, func(out io.Writer) error {
+		_, err := out.Write(data)
+		return err
+	}); err != nil {
+		framework.Logf("ERROR: %v", err)
+	}
+}
+
+// monotonicGrowth returns true if there are enough values, none of
+// them is smaller than the one before and the last one is larger
+// than the first.
+func monotonicGrowth(values []float64) bool {
+	if len(values) < minGrowthSamples || values[len(values)-1] <= values[0] {
+		return false
+	}
+	for i := 1; i < len(values); i++ {
+		if values[i] < values[i-1] {
+			return false
+		}
+	}
+	return true
+}
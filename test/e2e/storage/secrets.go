@@ -0,0 +1,145 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/test/e2e/framework"
+
+	. "github.com/onsi/gomega"
+)
+
+// secretUsage determines for which CSI operations a secret gets
+// referenced in the storage class.
+type secretUsage string
+
+const (
+	secretProvisioner       secretUsage = "provisioner"
+	secretControllerPublish secretUsage = "controller-publish"
+	secretNodeStage         secretUsage = "node-stage"
+	secretNodePublish       secretUsage = "node-publish"
+)
+
+// nameParameter returns the storage class parameter that contains
+// the name of the secret for this kind of usage.
+func (s secretUsage) nameParameter() string {
+	return "csi.storage.k8s.io/" + string(s) + "-secret-name"
+}
+
+// namespaceParameter returns the storage class parameter that
+// contains the namespace of the secret for this kind of usage.
+func (s secretUsage) namespaceParameter() string {
+	return "csi.storage.k8s.io/" + string(s) + "-secret-namespace"
+}
+
+// secretValue defines where the content of one entry in a secret
+// comes from. Exactly one of the fields must be set.
+type secretValue struct {
+	// Env is the name of an environment variable.
+	Env string
+	// File is the path of a local file.
+	File string
+}
+
+func (v secretValue) read() ([]byte, error) {
+	switch {
+	case v.Env != "":
+		value, ok := os.LookupEnv(v.Env)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s not set", v.Env)
+		}
+		return []byte(value), nil
+	case v.File != "":
+		return ioutil.ReadFile(v.File)
+	default:
+		return nil, fmt.Errorf("neither environment variable nor file specified")
+	}
+}
+
+// secretTemplate describes a secret that gets created in the test
+// namespace before each test. Because credentials must not be part of
+// the driver definition, the actual values are read at runtime.
+type secretTemplate struct {
+	// Name of the secret. It is used as-is because the
+	// secret gets created in the per-test namespace.
+	Name string
+	// Data defines the content of the secret.
+	Data map[string]secretValue
+	// Usage defines which storage class parameters
+	// reference the secret.
+	Usage []secretUsage
+}
+
+// createSecrets creates one secret for each template in the test
+// namespace. It returns the secrets and a cleanup function for them,
+// or an error.
+func createSecrets(f *framework.Framework, templates []secretTemplate) ([]*v1.Secret, func(), error) {
+	var secrets []*v1.Secret
+	var items []interface{}
+	for _, template := range templates {
+		secret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: template.Name,
+			},
+			Data: map[string][]byte{},
+		}
+		for key, value := range template.Data {
+			data, err := value.read()
+			if err != nil {
+				return nil, nil, fmt.Errorf("secret %s, key %s: %v", template.Name, key, err)
+			}
+			secret.Data[key] = data
+		}
+		secrets = append(secrets, secret)
+		items = append(items, secret)
+	}
+	if err := f.PatchItems(items...); err != nil {
+		return nil, nil, err
+	}
+	cleanup, err := f.CreateItems(items...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return secrets, cleanup, nil
+}
+
+// secretParameters returns the storage class parameters which
+// reference the secrets created for the templates.
+func secretParameters(templates []secretTemplate, secrets []*v1.Secret) map[string]string {
+	parameters := map[string]string{}
+	for i, template := range templates {
+		for _, usage := range template.Usage {
+			parameters[usage.nameParameter()] = secrets[i].Name
+			parameters[usage.namespaceParameter()] = secrets[i].Namespace
+		}
+	}
+	return parameters
+}
+
+// checkSecretsDeleted verifies that none of the secrets exists anymore.
+func checkSecretsDeleted(f *framework.Framework, secrets []*v1.Secret) {
+	for _, secret := range secrets {
+		_, err := f.ClientSet.CoreV1().Secrets(secret.Namespace).Get(secret.Name, metav1.GetOptions{})
+		Expect(apierrs.IsNotFound(err)).To(BeTrue(), "secret %s/%s should have been deleted, got: %v", secret.Namespace, secret.Name, err)
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"k8s.io/kubernetes/test/e2e/storage/testpatterns"
+	"k8s.io/kubernetes/test/e2e/storage/testsuites"
+)
+
+// nameSelection can be used multiple times on the command line, each
+// time with a comma-separated list of names. Nothing selected means
+// that everything is selected.
+type nameSelection struct {
+	names []string
+}
+
+func (n *nameSelection) String() string {
+	return strings.Join(n.names, ",")
+}
+
+func (n *nameSelection) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			n.names = append(n.names, name)
+		}
+	}
+	return nil
+}
+
+// selects returns true if the name was given or no names were given
+// at all.
+func (n *nameSelection) selects(name string) bool {
+	if len(n.names) == 0 {
+		return true
+	}
+	for _, selected := range n.names {
+		if selected == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validate returns an error for names which are not among the valid
+// names.
+func (n *nameSelection) validate(flagName string, valid []string) error {
+	for _, name := range n.names {
+		found := false
+		for _, v := range valid {
+			if v == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("-%s: unknown name %q, must be one of: %s", flagName, name, strings.Join(valid, ", "))
+		}
+	}
+	return nil
+}
+
+var selectedDrivers, selectedSuites, selectedPatterns nameSelection
+
+func init() {
+	flag.Var(&selectedDrivers, "csi.drivers",
+		"Only test the drivers with these names. Can be used multiple times, each time with a comma-separated list. Default is to test all drivers.")
+	flag.Var(&selectedSuites, "csi.suites",
+		"Only run the test suites with these names, for example subPath or read-only. Can be used multiple times, each time with a comma-separated list. Default is to run all test suites.")
+	flag.Var(&selectedPatterns, "csi.patterns",
+		"Only run tests with these test patterns, for example \"Dynamic PV (default fs)\". Can be used multiple times, each time with a comma-separated list. Default is to use all test patterns.")
+}
+
+// validateSelection checks the names given for -csi.drivers,
+// -csi.suites and -csi.patterns.
+func validateSelection() error {
+	var drivers []string
+	for _, initDriver := range csiTestDrivers {
+		drivers = append(drivers, initDriver(nil).GetDriverInfo().Name)
+	}
+	if err := selectedDrivers.validate("csi.drivers", drivers); err != nil {
+		return err
+	}
+
+	var suites []string
+	for _, suite := range csiTestSuites {
+		suites = append(suites, suite.name)
+	}
+	for _, initSuite := range csiExtraTestSuites {
+		suites = append(suites, initSuite().getTestSuiteInfo().name)
+	}
+	if err := selectedSuites.validate("csi.suites", suites); err != nil {
+		return err
+	}
+
+	var patterns []string
+	for _, pattern := range knownPatterns {
+		patterns = append(patterns, pattern.Name)
+	}
+	return selectedPatterns.validate("csi.patterns", patterns)
+}
+
+// selectedTestSuites returns the test suites from the testsuites
+// package which were selected on the command line.
+func selectedTestSuites() []func() testsuites.TestSuite {
+	var suites []func() testsuites.TestSuite
+	for _, suite := range csiTestSuites {
+		if selectedSuites.selects(suite.name) {
+			suites = append(suites, suite.init)
+		}
+	}
+	return suites
+}
+
+// selectedCSITestSuites returns the test suites from this package
+// which were selected on the command line.
+func selectedCSITestSuites() []func() csiTestSuite {
+	var suites []func() csiTestSuite
+	for _, initSuite := range csiExtraTestSuites {
+		if selectedSuites.selects(initSuite().getTestSuiteInfo().name) {
+			suites = append(suites, initSuite)
+		}
+	}
+	return suites
+}
+
+// selectPatterns removes all patterns which were not selected on
+// the command line.
+func selectPatterns(patterns []testpatterns.TestPattern) []testpatterns.TestPattern {
+	var selected []testpatterns.TestPattern
+	for _, pattern := range patterns {
+		if selectedPatterns.selects(pattern.Name) {
+			selected = append(selected, pattern)
+		}
+	}
+	return selected
+}
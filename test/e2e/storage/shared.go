@@ -0,0 +1,125 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"flag"
+	"path"
+
+	"k8s.io/kubernetes/test/e2e/framework"
+	"k8s.io/kubernetes/test/e2e/framework/podlogs"
+
+	. "github.com/onsi/ginkgo"
+)
+
+var shareDrivers bool
+
+func init() {
+	flag.BoolVar(&shareDrivers, "csi.share-drivers", false,
+		"Deploy each driver only once per Ginkgo node, in a separate namespace, and use that instance for all tests on the node instead of deploying it for each test. Objects created by tests remain in the per-test namespaces.")
+}
+
+// sharedDeployment is a driver instance that is used by all tests on
+// a Ginkgo node. The framework instance only provides the client,
+// namespace and unique name for patching and creating the driver
+// objects, it is not used to run tests.
+type sharedDeployment struct {
+	f        *framework.Framework
+	cleanup  func()
+	stopLogs context.CancelFunc
+	// Nil unless enabled with -csi.resource-usage-interval.
+	resources *resourceSampler
+	// Kept because of -csi.hold-on-failure.
+	held bool
+}
+
+// sharedDeployments contains all driver instances deployed on this
+// Ginkgo node.
+var sharedDeployments []*sharedDeployment
+
+// newSharedDeployment creates the namespace for a driver instance
+// that outlives the test which deploys it.
+func newSharedDeployment(f *framework.Framework) (*sharedDeployment, error) {
+	ns, err := CreateTestingNS("csi-driver", f.ClientSet, nil)
+	if err != nil {
+		return nil, err
+	}
+	shared := &sharedDeployment{
+		f: &framework.Framework{
+			BaseName:     "csi-driver",
+			ClientSet:    f.ClientSet,
+			CSIClientSet: f.CSIClientSet,
+			Namespace:    ns,
+			UniqueName:   ns.Name,
+		},
+	}
+	sharedDeployments = append(sharedDeployments, shared)
+	recordUniqueName(ns.Name)
+
+	// The test which happens to deploy the driver ends long
+	// before the driver, so its logs cannot go into that test's
+	// directory.
+	ctx, cancel := context.WithCancel(context.Background())
+	shared.stopLogs = cancel
+	to := podlogs.LogOutput{
+		StatusWriter: GinkgoWriter,
+	}
+	if dir := shared.reportDir(); dir == "" {
+		to.LogWriter = GinkgoWriter
+	} else {
+		to.LogPathPrefix = dir + "/"
+	}
+	podlogs.CopyAllLogs(ctx, f.ClientSet, ns.Name, to)
+	return shared, nil
+}
+
+// reportDir returns the directory for output related to the driver
+// instance, an empty string if there is no report directory.
+func (shared *sharedDeployment) reportDir() string {
+	if framework.TestContext.ReportDir == "" {
+		return ""
+	}
+	return path.Join(framework.TestContext.ReportDir, shared.f.Namespace.Name)
+}
+
+// CleanupSharedDrivers removes all driver instances that were
+// deployed on this Ginkgo node because of -csi.share-drivers. It
+// must be called on each node after all tests have completed.
+func CleanupSharedDrivers() {
+	for _, shared := range sharedDeployments {
+		ns := shared.f.Namespace.Name
+		shared.stopLogs()
+		shared.resources.finish(shared.reportDir())
+		if shared.held {
+			framework.Logf("keeping shared driver in namespace %s", ns)
+			continue
+		}
+		framework.Logf("removing shared driver in namespace %s", ns)
+		if shared.cleanup != nil {
+			shared.cleanup()
+		}
+		if err := shared.f.ClientSet.CoreV1().Namespaces().Delete(ns, nil); err != nil {
+			framework.Logf("ERROR: deleting namespace %s: %v", ns, err)
+			continue
+		}
+		if err := framework.WaitForNamespacesDeleted(shared.f.ClientSet, []string{ns}, framework.NamespaceCleanupTimeout); err != nil {
+			framework.Logf("ERROR: %v", err)
+		}
+	}
+	sharedDeployments = nil
+}
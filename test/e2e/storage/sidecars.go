@@ -0,0 +1,204 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/kubernetes/test/e2e/storage/testsuites"
+)
+
+// sidecarMatrix maps the name of a container image (the last path
+// element without tag, like "csi-provisioner") to all tags that
+// the image is supposed to be tested with.
+type sidecarMatrix map[string][]string
+
+// sidecarMatrixFlag can be used multiple times on the command line,
+// each time with <image name>=<tag>[,<tag>...].
+type sidecarMatrixFlag struct {
+	matrix sidecarMatrix
+}
+
+func (s *sidecarMatrixFlag) String() string {
+	var entries []string
+	for image, tags := range s.matrix {
+		entries = append(entries, image+"="+strings.Join(tags, ","))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, " ")
+}
+
+func (s *sidecarMatrixFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("expected <image name>=<tag>[,<tag>...], got %q", value)
+	}
+	tags := strings.Split(parts[1], ",")
+	for _, tag := range tags {
+		if tag == "" {
+			return fmt.Errorf("empty tag in %q", value)
+		}
+	}
+	if s.matrix == nil {
+		s.matrix = sidecarMatrix{}
+	}
+	s.matrix[parts[0]] = append(s.matrix[parts[0]], tags...)
+	return nil
+}
+
+var sidecarVersionsFlag sidecarMatrixFlag
+
+func init() {
+	flag.Var(&sidecarVersionsFlag, "csi.sidecar-versions",
+		"Run all tests for each combination of image versions. Can be used multiple times, each time with <image name>=<tag>[,<tag>...], for example csi-provisioner=v1.0.0,v1.0.1. Overrides the versions from the driver definition for the same image.")
+}
+
+// sidecarVersions maps image names to the tag that is to be used
+// for them.
+type sidecarVersions map[string]string
+
+// String returns a stable description of the versions, suitable
+// for inclusion in a test name.
+func (s sidecarVersions) String() string {
+	var entries []string
+	for image, tag := range s {
+		entries = append(entries, image+"="+tag)
+	}
+	sort.Strings(entries)
+	return "[Sidecars: " + strings.Join(entries, " ") + "]"
+}
+
+// combinations returns all combinations of versions defined by the
+// matrix, merged with the matrix from the command line. It always
+// returns at least one entry, which is nil if there is nothing to
+// patch.
+func (s sidecarMatrix) combinations() []sidecarVersions {
+	matrix := sidecarMatrix{}
+	for image, tags := range s {
+		matrix[image] = tags
+	}
+	for image, tags := range sidecarVersionsFlag.matrix {
+		matrix[image] = tags
+	}
+	var images []string
+	for image := range matrix {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+
+	result := []sidecarVersions{nil}
+	for _, image := range images {
+		var expanded []sidecarVersions
+		for _, versions := range result {
+			for _, tag := range matrix[image] {
+				combination := sidecarVersions{image: tag}
+				for i, t := range versions {
+					combination[i] = t
+				}
+				expanded = append(expanded, combination)
+			}
+		}
+		result = expanded
+	}
+	return result
+}
+
+// sidecarCombinations returns the image versions that the driver
+// needs to be tested with. Only manifestDriver supports patching
+// images, for all other drivers the result is a single nil entry.
+func sidecarCombinations(driver testsuites.TestDriver) []sidecarVersions {
+	m, ok := driver.(*manifestDriver)
+	if !ok {
+		return []sidecarVersions{nil}
+	}
+	return m.sidecarMatrix.combinations()
+}
+
+// patchSidecarImages replaces the tag of all container and init
+// container images listed in versions. It returns the names of the
+// images that were found.
+func patchSidecarImages(versions sidecarVersions, object interface{}) []string {
+	if len(versions) == 0 {
+		return nil
+	}
+
+	var patched []string
+	patchContainers := func(containers []v1.Container) {
+		for i := range containers {
+			container := &containers[i]
+			name, _ := splitImage(container.Image)
+			if tag, ok := versions[imageName(name)]; ok {
+				container.Image = name + ":" + tag
+				patched = append(patched, imageName(name))
+			}
+		}
+	}
+	patchPod := func(spec *v1.PodSpec) {
+		patchContainers(spec.InitContainers)
+		patchContainers(spec.Containers)
+	}
+
+	switch object := object.(type) {
+	case *appsv1.ReplicaSet:
+		patchPod(&object.Spec.Template.Spec)
+	case *appsv1.DaemonSet:
+		patchPod(&object.Spec.Template.Spec)
+	case *appsv1.StatefulSet:
+		patchPod(&object.Spec.Template.Spec)
+	case *appsv1.Deployment:
+		patchPod(&object.Spec.Template.Spec)
+	}
+	return patched
+}
+
+// unusedSidecarImages returns the names of the images in versions
+// which are not in patched, sorted alphabetically. Tests must not
+// claim to use an image version when the image was not found.
+func unusedSidecarImages(versions sidecarVersions, patched map[string]bool) []string {
+	var unused []string
+	for image := range versions {
+		if !patched[image] {
+			unused = append(unused, image)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// splitImage splits an image reference like
+// quay.io/k8scsi/csi-provisioner:v1.0.1 into repository and tag.
+// A port number in the registry host name is not mistaken for a tag.
+func splitImage(image string) (string, string) {
+	if at := strings.Index(image, "@"); at >= 0 {
+		image = image[:at]
+	}
+	colon := strings.LastIndex(image, ":")
+	if colon < 0 || strings.Contains(image[colon:], "/") {
+		return image, ""
+	}
+	return image[:colon], image[colon+1:]
+}
+
+// imageName returns the last element of an image repository name.
+func imageName(repository string) string {
+	return repository[strings.LastIndex(repository, "/")+1:]
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+)
+
+func TestSidecarMatrixFlag(t *testing.T) {
+	testcases := []struct {
+		value    string
+		expected sidecarMatrix
+	}{
+		{value: "csi-provisioner=v1.0.0", expected: sidecarMatrix{"csi-provisioner": {"v1.0.0"}}},
+		{value: "csi-provisioner=v1.0.0,v1.0.1", expected: sidecarMatrix{"csi-provisioner": {"v1.0.0", "v1.0.1"}}},
+		{value: "csi-provisioner"},
+		{value: "=v1.0.0"},
+		{value: "csi-provisioner="},
+		{value: "csi-provisioner=v1.0.0,"},
+		{value: "csi-provisioner=,v1.0.0"},
+		{value: "csi-provisioner=,"},
+	}
+	for _, tc := range testcases {
+		var s sidecarMatrixFlag
+		err := s.Set(tc.value)
+		switch {
+		case tc.expected == nil && err == nil:
+			t.Errorf("%q: expected error, got %v", tc.value, s.matrix)
+		case tc.expected != nil && err != nil:
+			t.Errorf("%q: unexpected error: %v", tc.value, err)
+		case tc.expected != nil && !reflect.DeepEqual(s.matrix, tc.expected):
+			t.Errorf("%q: expected %v, got %v", tc.value, tc.expected, s.matrix)
+		}
+	}
+}
+
+func TestPatchSidecarImages(t *testing.T) {
+	ds := &appsv1.DaemonSet{
+		Spec: appsv1.DaemonSetSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					InitContainers: []v1.Container{
+						{Image: "quay.io/k8scsi/csi-init:v0.1.0"},
+					},
+					Containers: []v1.Container{
+						{Image: "quay.io/k8scsi/csi-node-driver-registrar:v1.0.2"},
+						{Image: "localhost:5000/hostpathplugin"},
+					},
+				},
+			},
+		},
+	}
+	versions := sidecarVersions{
+		"csi-init":                  "v0.2.0",
+		"csi-node-driver-registrar": "v1.1.0",
+		"hostpathplugin":            "canary",
+		"csi-provisionr":            "v1.0.1",
+	}
+	patched := map[string]bool{}
+	for _, image := range patchSidecarImages(versions, ds) {
+		patched[image] = true
+	}
+
+	var images []string
+	for _, container := range ds.Spec.Template.Spec.InitContainers {
+		images = append(images, container.Image)
+	}
+	for _, container := range ds.Spec.Template.Spec.Containers {
+		images = append(images, container.Image)
+	}
+	expected := []string{
+		"quay.io/k8scsi/csi-init:v0.2.0",
+		"quay.io/k8scsi/csi-node-driver-registrar:v1.1.0",
+		"localhost:5000/hostpathplugin:canary",
+	}
+	if !reflect.DeepEqual(images, expected) {
+		t.Errorf("expected images %v, got %v", expected, images)
+	}
+	if unused := unusedSidecarImages(versions, patched); !reflect.DeepEqual(unused, []string{"csi-provisionr"}) {
+		t.Errorf("expected unused image csi-provisionr, got %v", unused)
+	}
+}
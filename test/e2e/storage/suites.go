@@ -0,0 +1,233 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/test/e2e/framework"
+	"k8s.io/kubernetes/test/e2e/storage/testpatterns"
+	"k8s.io/kubernetes/test/e2e/storage/testsuites"
+	imageutils "k8s.io/kubernetes/test/utils/image"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// csiTestSuite is the counterpart of testsuites.TestSuite for the
+// additional test suites defined in this package. The original
+// interface cannot be implemented outside of the testsuites package
+// because its methods are not exported.
+type csiTestSuite interface {
+	// getTestSuiteInfo returns the csiTestSuiteInfo for this csiTestSuite
+	getTestSuiteInfo() csiTestSuiteInfo
+	// skipUnsupportedTest skips the test if this csiTestSuite is not suitable to be tested with the combination of TestPattern and TestDriver
+	skipUnsupportedTest(testpatterns.TestPattern, testsuites.TestDriver)
+	// execTest executes test of the testpattern for the driver
+	execTest(testsuites.TestDriver, testpatterns.TestPattern)
+}
+
+// csiTestSuiteInfo represents a set of parameters for csiTestSuite
+type csiTestSuiteInfo struct {
+	name         string                     // name of the csiTestSuite
+	featureTag   string                     // featureTag for the csiTestSuite
+	testPatterns []testpatterns.TestPattern // Slice of TestPattern for the csiTestSuite
+}
+
+// getCSITestNameStr uses the same naming scheme as the testsuites
+// package, so test names look the same for both kinds of suites.
+func getCSITestNameStr(suite csiTestSuite, pattern testpatterns.TestPattern) string {
+	tsInfo := suite.getTestSuiteInfo()
+	return fmt.Sprintf("[Testpattern: %s]%s %s%s", pattern.Name, pattern.FeatureTag, tsInfo.name, tsInfo.featureTag)
+}
+
+// runCSITestSuites is the counterpart of testsuites.RunTestSuite.
+func runCSITestSuites(driver testsuites.TestDriver, tsInits []func() csiTestSuite, tunePatternFunc func([]testpatterns.TestPattern) []testpatterns.TestPattern) {
+	for _, testSuiteInit := range tsInits {
+		suite := testSuiteInit()
+		patterns := tunePatternFunc(suite.getTestSuiteInfo().testPatterns)
+
+		for _, pattern := range patterns {
+			suite.execTest(driver, pattern)
+		}
+	}
+}
+
+// skipUnsupportedCSITest follows the same steps as the testsuites
+// package when deciding whether a test must be skipped. The suites in
+// this package only support dynamically provisioned volumes.
+func skipUnsupportedCSITest(suite csiTestSuite, driver testsuites.TestDriver, pattern testpatterns.TestPattern) {
+	dInfo := driver.GetDriverInfo()
+
+	// 1. Check if Whether volType is supported by driver from its interface
+	if _, ok := driver.(testsuites.DynamicPVTestDriver); !ok || pattern.VolType != testpatterns.DynamicPV {
+		framework.Skipf("Driver %s doesn't support %v -- skipping", dInfo.Name, pattern.VolType)
+	}
+
+	// 2. Check if fsType is supported by driver
+	if !dInfo.SupportedFsType.Has(pattern.FsType) {
+		framework.Skipf("Driver %s doesn't support %v -- skipping", dInfo.Name, pattern.FsType)
+	}
+
+	// 3. Check with driver specific logic
+	driver.SkipUnsupportedTest(pattern)
+
+	// 4. Check with testSuite specific logic
+	suite.skipUnsupportedTest(pattern, driver)
+}
+
+// createStorageClass creates the storage class and returns the
+// created object together with a function that deletes it again.
+func createStorageClass(cs clientset.Interface, sc *storagev1.StorageClass) (*storagev1.StorageClass, func()) {
+	By("creating a StorageClass " + sc.Name)
+	sc, err := cs.StorageV1().StorageClasses().Create(sc)
+	framework.ExpectNoError(err, "create storage class")
+	return sc, func() {
+		framework.Logf("deleting storage class %s", sc.Name)
+		err := cs.StorageV1().StorageClasses().Delete(sc.Name, nil)
+		if err != nil && !apierrs.IsNotFound(err) {
+			framework.ExpectNoError(err, "delete storage class %s", sc.Name)
+		}
+	}
+}
+
+// newClaim returns a claim for the storage class with the given size.
+func newClaim(claimSize, ns string, sc *storagev1.StorageClass, accessModes ...v1.PersistentVolumeAccessMode) *v1.PersistentVolumeClaim {
+	if len(accessModes) == 0 {
+		accessModes = []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce}
+	}
+	return &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "pvc-",
+			Namespace:    ns,
+		},
+		Spec: v1.PersistentVolumeClaimSpec{
+			AccessModes: accessModes,
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{
+					v1.ResourceName(v1.ResourceStorage): resource.MustParse(claimSize),
+				},
+			},
+			StorageClassName: &sc.Name,
+		},
+	}
+}
+
+// createBoundClaim creates the claim, waits for it to be bound and
+// returns the updated claim and the PV bound to it.
+func createBoundClaim(cs clientset.Interface, claim *v1.PersistentVolumeClaim) (*v1.PersistentVolumeClaim, *v1.PersistentVolume) {
+	By("creating a claim")
+	claim, err := cs.CoreV1().PersistentVolumeClaims(claim.Namespace).Create(claim)
+	framework.ExpectNoError(err, "create claim")
+	err = framework.WaitForPersistentVolumeClaimPhase(v1.ClaimBound, cs, claim.Namespace, claim.Name, framework.Poll, framework.ClaimProvisionTimeout)
+	framework.ExpectNoError(err, "wait for claim %s to be bound", claim.Name)
+	claim, err = cs.CoreV1().PersistentVolumeClaims(claim.Namespace).Get(claim.Name, metav1.GetOptions{})
+	framework.ExpectNoError(err, "get claim %s", claim.Name)
+	pv, err := cs.CoreV1().PersistentVolumes().Get(claim.Spec.VolumeName, metav1.GetOptions{})
+	framework.ExpectNoError(err, "get PV %s", claim.Spec.VolumeName)
+	return claim, pv
+}
+
+// deleteClaim deletes the claim and, if the PV has reclaim policy
+// "Delete", waits for the PV to be removed.
+func deleteClaim(cs clientset.Interface, claim *v1.PersistentVolumeClaim, pv *v1.PersistentVolume) {
+	framework.Logf("deleting claim %s/%s", claim.Namespace, claim.Name)
+	err := cs.CoreV1().PersistentVolumeClaims(claim.Namespace).Delete(claim.Name, nil)
+	if err != nil && !apierrs.IsNotFound(err) {
+		framework.ExpectNoError(err, "delete claim %s", claim.Name)
+	}
+	if pv != nil && pv.Spec.PersistentVolumeReclaimPolicy == v1.PersistentVolumeReclaimDelete {
+		framework.ExpectNoError(framework.WaitForPersistentVolumeDeleted(cs, pv.Name, 5*time.Second, 5*time.Minute),
+			"PV %s not deleted by dynamic provisioner", pv.Name)
+	}
+}
+
+// newVolumeTesterPod returns a pod which runs the shell command
+// with the claim mounted at /mnt/test.
+func newVolumeTesterPod(ns, claimName string, readOnly bool, nodeName, command string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "pvc-volume-tester-",
+			Namespace:    ns,
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name:    "volume-tester",
+					Image:   imageutils.GetE2EImage(imageutils.BusyBox),
+					Command: []string{"/bin/sh"},
+					Args:    []string{"-c", command},
+					VolumeMounts: []v1.VolumeMount{
+						{
+							Name:      "my-volume",
+							MountPath: "/mnt/test",
+						},
+					},
+				},
+			},
+			RestartPolicy: v1.RestartPolicyNever,
+			NodeName:      nodeName,
+			Volumes: []v1.Volume{
+				{
+					Name: "my-volume",
+					VolumeSource: v1.VolumeSource{
+						PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+							ClaimName: claimName,
+							ReadOnly:  readOnly,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// runInPod creates the pod, waits for it to complete successfully,
+// logs its output and deletes it.
+func runInPod(cs clientset.Interface, pod *v1.Pod) {
+	pod, err := cs.CoreV1().Pods(pod.Namespace).Create(pod)
+	framework.ExpectNoError(err, "create pod")
+	defer func() {
+		body, err := cs.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &v1.PodLogOptions{}).Do().Raw()
+		if err != nil {
+			framework.Logf("Error getting logs for pod %s: %v", pod.Name, err)
+		} else {
+			framework.Logf("Pod %s has the following logs: %s", pod.Name, body)
+		}
+		framework.DeletePodOrFail(cs, pod.Namespace, pod.Name)
+		framework.ExpectNoError(framework.WaitForPodToDisappear(cs, pod.Namespace, pod.Name, labels.Everything(), framework.Poll, framework.PodDeleteTimeout))
+	}()
+	framework.ExpectNoError(framework.WaitForPodNotPending(cs, pod.Namespace, pod.Name))
+	framework.ExpectNoError(framework.WaitForPodSuccessInNamespaceSlow(cs, pod.Name, pod.Namespace))
+}
+
+// getDynamicProvisioningDriver returns the driver as
+// testsuites.DynamicPVTestDriver. skipUnsupportedCSITest ensures
+// that this works.
+func getDynamicProvisioningDriver(driver testsuites.TestDriver) testsuites.DynamicPVTestDriver {
+	dDriver, ok := driver.(testsuites.DynamicPVTestDriver)
+	Expect(ok).To(BeTrue(), "driver %s must support dynamic provisioning", driver.GetDriverInfo().Name)
+	return dDriver
+}